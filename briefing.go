@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+// 盤前簡報: 期貨早盤開盤 (08:45) 前 briefingLead 發送，每個交易日一次
+// 彙整前一個夜盤 (狀態中的夜盤高低點與收盤)、加權前日收盤與美股收盤
+const briefingLead = 15 * time.Minute
+
+// morningOpen t 當天的期貨早盤開盤時間
+func morningOpen(t time.Time) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 8, 45, 0, 0, loc)
+}
+
+// IsBriefingTime 是否為盤前簡報時段 (08:30 ~ 08:44)
+func IsBriefingTime(t time.Time) bool {
+	open := morningOpen(t)
+	return !t.Before(open.Add(-briefingLead)) && t.Before(open)
+}
+
+// Briefing 盤前簡報
+type Briefing struct {
+	Day string // 簡報日
+
+	NightDay   string  // 夜盤開盤日 (空字串代表沒有夜盤紀錄)
+	NightClose float64 // 夜盤期貨收盤
+	NightHigh  float64
+	NightLow   float64
+	DayClose   float64 // 夜盤前的日盤期貨收盤 (0 代表沒有紀錄)
+
+	SpotDay   string  // 加權收盤日
+	SpotClose float64 // 加權前日收盤 (0 代表沒有紀錄)
+
+	Indices  []IndexQuote // 美股收盤
+	IndexErr error        // 美股指數取得失敗的原因
+}
+
+// NewBriefing 以狀態中的夜盤與前日收盤、美股收盤彙整盤前簡報 (都沒有資料時回傳 false)
+func NewBriefing(now time.Time, d *Data, indices []IndexQuote, indexErr error) (*Briefing, bool) {
+	b := &Briefing{Day: now.In(loc).Format(time.DateOnly), Indices: indices, IndexErr: indexErr}
+
+	// 夜盤在日盤之後開盤，夜盤紀錄比日盤舊代表最近的夜盤沒有執行 (LastFutureValue 已是日盤收盤)
+	if d.NightTradingDay != "" && d.NightFutureHigh > 0 && d.NightTradingDay >= d.TradingDay {
+		b.NightDay = d.NightTradingDay
+		b.NightClose, b.NightHigh, b.NightLow = d.LastFutureValue, d.NightFutureHigh, d.NightFutureLow
+		if d.PrevSession == SessionMorning {
+			b.DayClose = d.PrevClose
+		}
+	}
+	if d.TradingDay != "" && d.LastTWIIValue > 0 {
+		b.SpotDay, b.SpotClose = d.TradingDay, d.LastTWIIValue
+	}
+
+	if b.NightDay == "" && b.SpotClose == 0 && len(indices) == 0 {
+		return nil, false
+	}
+	return b, true
+}
+
+// LargestMove 夜盤相對日盤收盤的最大波動 (上漲或下跌中幅度較大者) 與當時的價位
+func (b *Briefing) LargestMove() (float64, float64) {
+	up, down := b.NightHigh-b.DayClose, b.NightLow-b.DayClose
+	if math.Abs(down) > math.Abs(up) {
+		return down, b.NightLow
+	}
+	return up, b.NightHigh
+}
+
+// formatChange 漲跌點數與幅度
+func formatChange(change, base float64) string {
+	if base == 0 {
+		return fmt.Sprintf("%+.2f", change)
+	}
+	return fmt.Sprintf("%+.2f, %+.2f%%", change, change/base*100)
+}
+
+// Message 盤前簡報的通知內容
+func (b *Briefing) Message() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "🌅 [盤前簡報] %s\n", b.Day)
+
+	if b.NightDay == "" {
+		s.WriteString("夜盤: 無紀錄\n")
+	} else {
+		fmt.Fprintf(&s, "夜盤 (%s 開盤)\n", b.NightDay)
+		if b.DayClose > 0 {
+			fmt.Fprintf(&s, "期貨收盤: %.2f (較日盤收盤 %.2f: %s)\n", b.NightClose, b.DayClose, formatChange(b.NightClose-b.DayClose, b.DayClose))
+		} else {
+			fmt.Fprintf(&s, "期貨收盤: %.2f\n", b.NightClose)
+		}
+		fmt.Fprintf(&s, "區間: %.2f ~ %.2f (振幅 %.2f)\n", b.NightLow, b.NightHigh, b.NightHigh-b.NightLow)
+		if b.DayClose > 0 {
+			move, at := b.LargestMove()
+			fmt.Fprintf(&s, "最大波動: %+.2f (%.2f)\n", move, at)
+		}
+	}
+
+	if b.SpotClose > 0 {
+		fmt.Fprintf(&s, "加權收盤 (%s): %.2f\n", b.SpotDay, b.SpotClose)
+	}
+
+	if len(b.Indices) > 0 {
+		s.WriteString("美股收盤\n")
+		for _, q := range b.Indices {
+			fmt.Fprintf(&s, "%s: %.2f (%s)\n", q.Name, q.Close, formatChange(q.Change(), q.PrevClose))
+		}
+	}
+	if b.IndexErr != nil {
+		fmt.Fprintf(&s, "⚠️ 美股指數取得失敗: %v\n", b.IndexErr)
+	}
+	return strings.TrimSuffix(s.String(), "\n")
+}
+
+// brief 早盤開盤前發送盤前簡報 (每個交易日一次)
+func (r *Runner) brief(ctx context.Context) error {
+	now := r.Clock.Now()
+	if !IsBriefingTime(now) {
+		return nil
+	}
+	day := now.In(loc).Format(time.DateOnly)
+
+	var indices []IndexQuote
+	var indexErr error
+	fetchIndices := func() {
+		if r.Indices == nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(ctx, r.Config.ScrapeDeadline)
+		defer cancel()
+		if indices, indexErr = FetchIndices(ctx, r.Indices, r.Config.USIndexSymbols); indexErr != nil {
+			log.Printf("⚠️ 美股指數取得失敗: %v", indexErr)
+		}
+	}
+
+	return r.notifyOnce(ctx, "盤前簡報",
+		func(d *Data) bool { return d.LastBriefing == day },
+		func(d *Data) { d.LastBriefing = day },
+		fetchIndices,
+		func(d *Data) (string, bool) {
+			b, ok := NewBriefing(now, d, indices, indexErr)
+			if !ok {
+				fmt.Fprintln(r.out(), "沒有夜盤、前日收盤與美股資料，不發送盤前簡報。")
+				return "", false
+			}
+			return b.Message(), true
+		})
+}
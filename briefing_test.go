@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewBriefing(t *testing.T) {
+	now := time.Date(2026, 10, 16, 8, 30, 0, 0, loc)
+	// 10/15 日盤收盤 27000 (加權 27050)，夜盤 26900 ~ 27120，收在 27050
+	night := func() *Data {
+		return &Data{
+			LastTWIIValue: 27050, LastFutureValue: 27050,
+			TradingDay:      "2026-10-15",
+			NightTradingDay: "2026-10-15", NightFutureHigh: 27120, NightFutureLow: 26900,
+			PrevSession: SessionMorning, PrevClose: 27000, PrevHigh: 27080, PrevLow: 26950,
+		}
+	}
+	dji := IndexQuote{Symbol: "^DJI", Name: "道瓊", Close: 46190.61, PrevClose: 45952.24}
+
+	tests := []struct {
+		name      string
+		data      *Data
+		indices   []IndexQuote
+		indexErr  error
+		wantOK    bool
+		wantMsg   []string // 通知應包含的關鍵字
+		wantNoMsg []string // 通知不應包含的關鍵字
+	}{
+		{
+			name:    "夜盤與美股",
+			data:    night(),
+			indices: []IndexQuote{dji},
+			wantOK:  true,
+			wantMsg: []string{
+				"盤前簡報] 2026-10-16",
+				"夜盤 (2026-10-15 開盤)",
+				"期貨收盤: 27050.00 (較日盤收盤 27000.00: +50.00, +0.19%)",
+				"區間: 26900.00 ~ 27120.00 (振幅 220.00)",
+				"最大波動: +120.00 (27120.00)",
+				"加權收盤 (2026-10-15): 27050.00",
+				"道瓊: 46190.61 (+238.37, +0.52%)",
+			},
+			wantNoMsg: []string{"取得失敗"},
+		},
+		{
+			name: "夜盤下跌較多_最大波動為下跌",
+			data: func() *Data {
+				d := night()
+				d.NightFutureLow = 26850
+				return d
+			}(),
+			wantOK:    true,
+			wantMsg:   []string{"最大波動: -150.00 (26850.00)"},
+			wantNoMsg: []string{"美股收盤"},
+		},
+		{
+			name: "夜盤沒有執行_不沿用舊的夜盤紀錄",
+			data: func() *Data {
+				d := night()
+				d.TradingDay, d.NightTradingDay = "2026-10-15", "2026-10-14"
+				return d
+			}(),
+			indices:   []IndexQuote{dji},
+			indexErr:  errors.New("^SOX: 連線逾時"),
+			wantOK:    true,
+			wantMsg:   []string{"夜盤: 無紀錄", "加權收盤 (2026-10-15)", "美股指數取得失敗: ^SOX: 連線逾時"},
+			wantNoMsg: []string{"最大波動"},
+		},
+		{
+			name:   "沒有任何資料_不發送",
+			data:   &Data{},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, ok := NewBriefing(now, tt.data, tt.indices, tt.indexErr)
+			if ok != tt.wantOK {
+				t.Fatalf("NewBriefing() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			msg := b.Message()
+			for _, want := range tt.wantMsg {
+				if !strings.Contains(msg, want) {
+					t.Errorf("Message() = %q, want substring %q", msg, want)
+				}
+			}
+			for _, unwanted := range tt.wantNoMsg {
+				if strings.Contains(msg, unwanted) {
+					t.Errorf("Message() = %q, want no substring %q", msg, unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestRunner_RunOnce_Briefing(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 10, 16, 8, 20, 0, 0, loc)) // 週五
+	mem := NewMemoryStore()
+	mem.Save(context.Background(), &Data{
+		LastTWIIValue: 27050, LastFutureValue: 27050,
+		TradingDay:      "2026-10-15",
+		NightTradingDay: "2026-10-15", NightFutureHigh: 27120, NightFutureLow: 26900,
+		PrevSession: SessionMorning, PrevClose: 27000,
+		LastSummary: "2026-10-15 Night", // 夜盤收盤摘要已發送
+	})
+	notifier := &recordNotifier{}
+	indices := &fakeIndexSource{quotes: map[string]IndexQuote{
+		"^DJI":  {Name: "Dow Jones Industrial Average", Close: 46190.61, PrevClose: 45952.24},
+		"^IXIC": {Name: "NASDAQ Composite", Close: 22670.08, PrevClose: 22521.70},
+	}}
+
+	cfg := newTestConfig()
+	cfg.USIndexSymbols = []string{"^DJI", "^IXIC"}
+	r := NewRunner(cfg, StaticSources{&fakeSource{name: "fake"}}, mem, notifier)
+	r.Clock = clock
+	r.Indices = indices
+
+	// 08:20 尚未到簡報時間；08:30 之後排程重複觸發只發送一次
+	for _, at := range []time.Time{
+		time.Date(2026, 10, 16, 8, 20, 0, 0, loc),
+		time.Date(2026, 10, 16, 8, 30, 0, 0, loc),
+		time.Date(2026, 10, 16, 8, 35, 0, 0, loc),
+		time.Date(2026, 10, 16, 8, 40, 0, 0, loc),
+	} {
+		clock.Set(at)
+		if err := r.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce() at %v unexpected error: %v", at, err)
+		}
+	}
+
+	if len(notifier.msgs) != 1 {
+		t.Fatalf("RunOnce() alerts = %q, want exactly 1 briefing", notifier.msgs)
+	}
+	for _, want := range []string{"盤前簡報] 2026-10-16", "較日盤收盤 27000.00: +50.00", "道瓊: 46190.61", "那斯達克: 22670.08"} {
+		if !strings.Contains(notifier.msgs[0], want) {
+			t.Errorf("RunOnce() briefing = %q, want substring %q", notifier.msgs[0], want)
+		}
+	}
+	// 已發送後不再抓取美股指數
+	if indices.calls != 2 {
+		t.Errorf("FetchIndex() calls = %d, want 2", indices.calls)
+	}
+	if saved, _ := mem.Load(context.Background()); saved.LastBriefing != "2026-10-16" {
+		t.Errorf("RunOnce() saved LastBriefing = %q, want %q", saved.LastBriefing, "2026-10-16")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 取得目前時間 (測試時可替換為固定時間)
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock 系統時間
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FakeClock 手動控制的時間 (測試用)
+type FakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{t: t}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+// Set 設定目前時間
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+// Advance 時間前進 d
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
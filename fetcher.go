@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 預設的請求標頭 (模擬一般瀏覽器，避免被當成機器人擋下)
+const (
+	DefaultUserAgent      = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Safari/537.36"
+	DefaultAcceptLanguage = "zh-TW,zh;q=0.9,en;q=0.8"
+)
+
+// 回應內容上限，避免異常頁面吃光記憶體
+const maxResponseSize = 10 << 20
+
+// Backoff 指數退避重試 (含隨機抖動)
+type Backoff struct {
+	Attempts int           // 最多嘗試次數 (含第一次)
+	Base     time.Duration // 第一次重試前的等待時間，之後每次加倍
+	Max      time.Duration // 等待時間上限
+}
+
+// permanentError 不需要重試的錯誤 (例如 404)
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 包裝不需要重試的錯誤
+func Permanent(err error) error {
+	return &permanentError{err}
+}
+
+// Delay 第 attempt 次重試前的等待時間 (attempt 從 1 開始)
+// 取 [d/2, d] 之間的隨機值，避免多個排程同時重試
+func (b Backoff) Delay(attempt int) time.Duration {
+	d := b.Base << (attempt - 1)
+	if d <= 0 || (b.Max > 0 && d > b.Max) {
+		d = b.Max
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// Do 執行 fn，失敗時依退避規則重試，回傳最後一次的錯誤
+// ctx 取消時不再重試
+func (b Backoff) Do(ctx context.Context, fn func(attempt int) error) error {
+	attempts := max(b.Attempts, 1)
+
+	var err error
+	for i := 1; i <= attempts; i++ {
+		if err = fn(i); err == nil {
+			return nil
+		}
+
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		if i < attempts {
+			if sleepErr := Sleep(ctx, b.Delay(i)); sleepErr != nil {
+				return errors.Join(err, sleepErr)
+			}
+		}
+	}
+	return err
+}
+
+// Sleep 等待 d，ctx 先取消時提前回傳 ctx 的錯誤
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Fetcher 共用的 HTTP 抓取器 (逾時、重試、標頭、gzip 與 Proxy)
+type Fetcher struct {
+	Client         *http.Client
+	Timeout        time.Duration // 每次請求的期限
+	Retry          Backoff
+	UserAgent      string
+	AcceptLanguage string
+}
+
+// NewFetcher 依設定建立 Fetcher
+func NewFetcher(cfg *Config) (*Fetcher, error) {
+	proxy := http.ProxyFromEnvironment
+	if cfg.ScrapeProxy != "" {
+		u, err := url.Parse(cfg.ScrapeProxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("SCRAPE_PROXY 不是有效的網址: %q", cfg.ScrapeProxy)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	// 自行處理 gzip，才能確保 Accept-Encoding 標頭與解壓縮行為一致
+	transport.DisableCompression = true
+
+	f := &Fetcher{
+		Client:         &http.Client{Transport: transport},
+		Timeout:        cfg.HTTPTimeout,
+		Retry:          Backoff{Attempts: cfg.HTTPRetries + 1, Base: cfg.HTTPRetryBase, Max: cfg.HTTPRetryMax},
+		UserAgent:      cfg.UserAgent,
+		AcceptLanguage: cfg.AcceptLanguage,
+	}
+	return f, nil
+}
+
+// Get 下載網頁內容
+func (f *Fetcher) Get(ctx context.Context, urlLink string) ([]byte, error) {
+	return f.do(ctx, http.MethodGet, urlLink, "", nil)
+}
+
+// Post 送出 POST 請求並回傳回應內容
+func (f *Fetcher) Post(ctx context.Context, urlLink, contentType string, body []byte) ([]byte, error) {
+	return f.do(ctx, http.MethodPost, urlLink, contentType, body)
+}
+
+func (f *Fetcher) do(ctx context.Context, method, urlLink, contentType string, body []byte) ([]byte, error) {
+	var result []byte
+	err := f.Retry.Do(ctx, func(attempt int) error {
+		b, err := f.once(ctx, method, urlLink, contentType, body)
+		if err != nil {
+			if attempt < max(f.Retry.Attempts, 1) && ctx.Err() == nil {
+				fmt.Printf("⚠️ 請求失敗 (%d/%d)，稍後重試: %v\n", attempt, f.Retry.Attempts, err)
+			}
+			return err
+		}
+		result = b
+		return nil
+	})
+	return result, err
+}
+
+// once 執行單次請求 (期限為 ctx 與 Timeout 中較早者)
+func (f *Fetcher) once(ctx context.Context, method, urlLink, contentType string, body []byte) ([]byte, error) {
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, urlLink, bytes.NewReader(body))
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if f.UserAgent != "" {
+		req.Header.Set("User-Agent", f.UserAgent)
+	}
+	if f.AcceptLanguage != "" {
+		req.Header.Set("Accept-Language", f.AcceptLanguage)
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("HTTP 狀態異常: %s", resp.Status)
+		// 5xx 與 429 可能是暫時性的，其餘 (404、403...) 重試也沒用
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return nil, Permanent(err)
+		}
+		return nil, err
+	}
+
+	var reader io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("gzip 解壓縮失敗: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	return io.ReadAll(io.LimitReader(reader, maxResponseSize))
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestFetcher 測試用的 Fetcher (不重試)
+func newTestFetcher() *Fetcher {
+	return &Fetcher{Client: &http.Client{}, Timeout: 5 * time.Second}
+}
+
+func TestFetcher_Get(t *testing.T) {
+	fastRetry := Backoff{Attempts: 3, Base: time.Millisecond, Max: 5 * time.Millisecond}
+
+	tests := []struct {
+		name      string
+		handler   func(calls int32, w http.ResponseWriter, r *http.Request)
+		retry     Backoff
+		timeout   time.Duration
+		wantBody  string
+		wantErr   bool
+		wantCalls int32
+	}{
+		{
+			name: "成功",
+			handler: func(calls int32, w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			},
+			retry:     fastRetry,
+			wantBody:  "ok",
+			wantCalls: 1,
+		},
+		{
+			name: "伺服器錯誤兩次後成功",
+			handler: func(calls int32, w http.ResponseWriter, r *http.Request) {
+				if calls < 3 {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				w.Write([]byte("ok"))
+			},
+			retry:     fastRetry,
+			wantBody:  "ok",
+			wantCalls: 3,
+		},
+		{
+			name: "持續失敗_重試用盡",
+			handler: func(calls int32, w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			retry:     fastRetry,
+			wantErr:   true,
+			wantCalls: 3,
+		},
+		{
+			name: "404_不重試",
+			handler: func(calls int32, w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			retry:     fastRetry,
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name: "回應過慢_逾時後重試成功",
+			handler: func(calls int32, w http.ResponseWriter, r *http.Request) {
+				if calls == 1 {
+					select {
+					case <-time.After(time.Second):
+					case <-r.Context().Done():
+					}
+					return
+				}
+				w.Write([]byte("ok"))
+			},
+			retry:     fastRetry,
+			timeout:   50 * time.Millisecond,
+			wantBody:  "ok",
+			wantCalls: 2,
+		},
+		{
+			name: "gzip_回應自動解壓縮",
+			handler: func(calls int32, w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Accept-Encoding") != "gzip" {
+					w.Write([]byte("plain"))
+					return
+				}
+				w.Header().Set("Content-Encoding", "gzip")
+				gz := gzip.NewWriter(w)
+				gz.Write([]byte("壓縮內容"))
+				gz.Close()
+			},
+			retry:     fastRetry,
+			wantBody:  "壓縮內容",
+			wantCalls: 1,
+		},
+		{
+			name: "送出設定的標頭",
+			handler: func(calls int32, w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(r.Header.Get("User-Agent") + "|" + r.Header.Get("Accept-Language")))
+			},
+			retry:     fastRetry,
+			wantBody:  "watchtwii-test|zh-TW",
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(calls.Add(1), w, r)
+			}))
+			defer srv.Close()
+
+			timeout := tt.timeout
+			if timeout == 0 {
+				timeout = 5 * time.Second
+			}
+			cfg := &Config{
+				HTTPTimeout:    timeout,
+				HTTPRetries:    tt.retry.Attempts - 1,
+				HTTPRetryBase:  tt.retry.Base,
+				HTTPRetryMax:   tt.retry.Max,
+				UserAgent:      "watchtwii-test",
+				AcceptLanguage: "zh-TW",
+			}
+			f, err := NewFetcher(cfg)
+			if err != nil {
+				t.Fatalf("NewFetcher() unexpected error: %v", err)
+			}
+
+			body, err := f.Get(context.Background(), srv.URL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("Get() body = %q, want %q", body, tt.wantBody)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("Get() calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestFetcher_Proxy(t *testing.T) {
+	// 模擬 Proxy: 收到的請求會是完整的目標網址
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("via proxy " + r.URL.String()))
+	}))
+	defer proxy.Close()
+
+	f, err := NewFetcher(&Config{HTTPTimeout: 5 * time.Second, ScrapeProxy: proxy.URL})
+	if err != nil {
+		t.Fatalf("NewFetcher() unexpected error: %v", err)
+	}
+
+	body, err := f.Get(context.Background(), "http://quote.example.com/twii")
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(body), "via proxy http://quote.example.com/twii") {
+		t.Errorf("Get() body = %q, want request through proxy", body)
+	}
+
+	if _, err := NewFetcher(&Config{ScrapeProxy: "://bad"}); err == nil {
+		t.Errorf("NewFetcher() with invalid proxy want error")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	b := Backoff{Attempts: 5, Base: 100 * time.Millisecond, Max: 300 * time.Millisecond}
+
+	// 等待時間加倍且不超過上限，抖動範圍為 [d/2, d]
+	wantMax := []time.Duration{100, 200, 300, 300}
+	for i, w := range wantMax {
+		w *= time.Millisecond
+		for range 20 {
+			if d := b.Delay(i + 1); d < w/2 || d > w {
+				t.Fatalf("Delay(%d) = %v, want between %v and %v", i+1, d, w/2, w)
+			}
+		}
+	}
+
+	// Permanent 錯誤立即停止
+	calls := 0
+	errStop := errors.New("stop")
+	err := Backoff{Attempts: 3}.Do(context.Background(), func(attempt int) error {
+		calls++
+		return Permanent(errStop)
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("Do() = (%v, calls %d), want errStop after 1 call", err, calls)
+	}
+}
+
+func TestFetcher_Cancel(t *testing.T) {
+	// 伺服器一直不回應，直到請求被取消
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	f, err := NewFetcher(&Config{HTTPTimeout: 5 * time.Second, HTTPRetries: 2, HTTPRetryBase: time.Second, HTTPRetryMax: time.Second})
+	if err != nil {
+		t.Fatalf("NewFetcher() unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err = f.Get(ctx, srv.URL)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Get() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Get() took %v, want return right after cancel", elapsed)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Get() calls = %d, want no retry after cancel", got)
+	}
+}
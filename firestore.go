@@ -2,173 +2,311 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
-	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Firestore 設定
 const (
-	FirestoreCollection = "TraderAlerts"
-	FirestoreDocID      = "WatchTwiiDiff"
+	FirestoreCollection    = "TraderAlerts"
+	FirestoreDocID         = "WatchTwiiDiff"
+	FirestoreSelectorDocID = "WatchTwiiSelectors" // 爬蟲設定覆寫 (欄位同 SELECTORS_FILE)
 )
 
+// Data 狀態文件 (Firestore 以 firestore 標籤對應欄位，舊版文件讀取時由 MigrateData 升級)
 type Data struct {
-	LastTWIIValue  float64
-	LastDiffValue  float64
-	LastUpdateTime time.Time
+	SchemaVersion int `firestore:"SchemaVersion"` // 文件格式版本 (見 currentSchemaVersion)
+
+	LastTWIIValue  float64   `firestore:"LastTWIIValue"`
+	LastDiffValue  float64   `firestore:"LastDiffValue"`
+	LastUpdateTime time.Time `firestore:"LastUpdateTime"`
 
 	// --- 當日高低點紀錄 ---
-	SpotHigh   float64 // 現貨當日最高
-	SpotLow    float64 // 現貨當日最低
-	FutureHigh float64 // 期貨當日最高
-	FutureLow  float64 // 期貨當日最低
+	TradingDay string  `firestore:"TradingDay"` // 早盤高低點所屬的交易日 (2006-01-02)
+	SpotHigh   float64 `firestore:"SpotHigh"`   // 現貨當日最高
+	SpotLow    float64 `firestore:"SpotLow"`    // 現貨當日最低
+	FutureHigh float64 `firestore:"FutureHigh"` // 期貨當日最高
+	FutureLow  float64 `firestore:"FutureLow"`  // 期貨當日最低
+
+	// --- 夜盤高低點紀錄 (與早盤分開) ---
+	NightTradingDay string  `firestore:"NightTradingDay"` // 夜盤高低點所屬的夜盤開盤日 (2006-01-02)
+	NightFutureHigh float64 `firestore:"NightFutureHigh"` // 夜盤期貨最高
+	NightFutureLow  float64 `firestore:"NightFutureLow"`  // 夜盤期貨最低
+
+	// --- 前一盤紀錄 (換盤時由 ResetSession 保存) ---
+	PrevSession string  `firestore:"PrevSession"` // 前一盤的盤別 (空字串代表尚無紀錄)
+	PrevClose   float64 `firestore:"PrevClose"`   // 前一盤期貨收盤
+	PrevHigh    float64 `firestore:"PrevHigh"`    // 前一盤期貨最高
+	PrevLow     float64 `firestore:"PrevLow"`     // 前一盤期貨最低
+
+	// --- 報價來源 ---
+	SpotSource     string `firestore:"SpotSource"`     // 加權指數採用的來源
+	FutureSource   string `firestore:"FutureSource"`   // 台指期採用的來源
+	FutureContract string `firestore:"FutureContract"` // 台指期合約代碼 (例如: TXFK5)
+	SourceNote     string `firestore:"SourceNote"`     // 來源不一致、取得時間差距過大等提示 (空字串代表正常)
+
+	// --- 報價停滯檢查 ---
+	LastFutureValue   float64   `firestore:"LastFutureValue"`   // 前次期貨
+	SpotQuoteTime     time.Time `firestore:"SpotQuoteTime"`     // 前次加權的報價時間
+	FutureQuoteTime   time.Time `firestore:"FutureQuoteTime"`   // 前次期貨的報價時間
+	SpotRepeatSince   time.Time `firestore:"SpotRepeatSince"`   // 加權開始重複的時間 (零值代表有變動)
+	FutureRepeatSince time.Time `firestore:"FutureRepeatSince"` // 期貨開始重複的時間 (零值代表有變動)
 
 	// 錯誤處理
-	ErrorCount int    // 連續失敗計數
-	LastError  string // 記錄最後一次錯誤訊息
+	ErrorCount     int       `firestore:"ErrorCount"`     // 連續失敗計數
+	LastError      string    `firestore:"LastError"`      // 記錄最後一次錯誤訊息
+	ErrorSince     time.Time `firestore:"ErrorSince"`     // 這次連續失敗的開始時間
+	LastErrorAlert time.Time `firestore:"LastErrorAlert"` // 最後一次發送異常通知的時間
+
+	// --- 收盤摘要與盤前簡報 (見 summary.go, briefing.go) ---
+	StatsSession   string `firestore:"StatsSession"`   // 統計所屬的盤別 (sessionKey)
+	StatsAlerts    int    `firestore:"StatsAlerts"`    // 本盤發送的行情通知數
+	StatsErrors    int    `firestore:"StatsErrors"`    // 本盤抓取失敗次數
+	StatsLastError string `firestore:"StatsLastError"` // 本盤最後一次錯誤
+	LastSummary    string `firestore:"LastSummary"`    // 最後發送收盤摘要的盤別 (sessionKey)
+	LastBriefing   string `firestore:"LastBriefing"`   // 最後發送盤前簡報的日期 (2006-01-02)
+
+	revision int64 // 讀取時的版本 (由 StateStore 設定，不儲存)，0 代表尚未儲存過
 }
 
-func (d *Data) Map() map[string]interface{} {
-	return map[string]interface{}{
-		"LastTWIIValue":  d.LastTWIIValue,
-		"LastDiffValue":  d.LastDiffValue,
-		"LastUpdateTime": d.LastUpdateTime, // 直接存 Time 物件
-
-		// --- 新增映射 ---
-		"SpotHigh":   d.SpotHigh,
-		"SpotLow":    d.SpotLow,
-		"FutureHigh": d.FutureHigh,
-		"FutureLow":  d.FutureLow,
+// SetSources 記錄本次採用的報價來源與不一致提示
+func (d *Data) SetSources(res ScrapeResult) {
+	d.SpotSource = res.Spot.Source
+	d.FutureSource = res.Future.Source
+	d.SourceNote = strings.Join(res.Notes, "\n")
+}
 
-		"ErrorCount": d.ErrorCount,
-		"LastError":  d.LastError,
+// SourceInfo 通知訊息附加的來源說明
+func (d *Data) SourceInfo() string {
+	future := d.FutureSource
+	if d.FutureContract != "" {
+		future += " " + d.FutureContract
+	}
+	s := fmt.Sprintf("\n來源: 加權(%s) 期貨(%s)", d.SpotSource, future)
+	if d.SourceNote != "" {
+		s += "\n⚠️ " + d.SourceNote
 	}
+	return s
 }
 
-func (d *Data) Clone(m map[string]interface{}) *Data {
-	// 輔助函式：安全讀取 float64
-	getFloat := func(key string) float64 {
-		if val, ok := m[key]; ok {
-			if v, isFloat := val.(float64); isFloat {
-				return v
-			}
-		}
-		return 0.0
+// CheckRollover 檢查台指期近月合約是否已轉倉
+// contract 為來源回報的合約代碼，來源未提供時以結算日曆推算
+// 轉倉時重置期貨高低點基準，並回傳轉倉通知 (取代本次的價差警示)
+func (d *Data) CheckRollover(contract string, futureVal float64, now time.Time, loc *time.Location) (string, bool) {
+	if contract == "" {
+		contract = NearMonthContract(now, loc)
 	}
 
-	d.LastTWIIValue = getFloat("LastTWIIValue")
-	d.LastDiffValue = getFloat("LastDiffValue")
+	prev := d.FutureContract
+	d.FutureContract = contract
+	if prev == "" || prev == contract {
+		// 第一次運行或未轉倉
+		return "", false
+	}
 
-	// --- 新增讀取 ---
-	d.SpotHigh = getFloat("SpotHigh")
-	d.SpotLow = getFloat("SpotLow")
-	d.FutureHigh = getFloat("FutureHigh")
-	d.FutureLow = getFloat("FutureLow")
+	// 新合約與舊合約之間有月份價差，舊的高低點已無比較意義
+	d.FutureHigh = futureVal
+	d.FutureLow = futureVal
+	d.NightFutureHigh = futureVal
+	d.NightFutureLow = futureVal
 
-	if val, ok := m["LastUpdateTime"]; ok {
-		// Firestore 儲存時間通常是 time.Time，但也可能被讀為 int64 (如果是舊資料)
-		if v, isTime := val.(time.Time); isTime {
-			d.LastUpdateTime = v
-		} else if v, isInt64 := val.(int64); isInt64 {
-			d.LastUpdateTime = time.Unix(v, 0)
-		}
-	}
+	return fmt.Sprintf("🔄 [合約轉倉] 台指期近月合約由 %s 轉為 %s\n期貨: %.2f\n期貨高低點與價差基準已重置，本次不發送價差警示",
+		prev, contract, futureVal), true
+}
 
-	if val, ok := m["ErrorCount"]; ok {
-		if v, isInt := val.(int64); isInt {
-			d.ErrorCount = int(v)
-		} else if v, isInt := val.(int); isInt {
-			d.ErrorCount = v
-		}
+// PriceRefs 報價偏離檢查用的加權與期貨前值 (0 代表不檢查偏離)
+// 前值所屬的盤別早於前一個交易日 (停機數日) 時不檢查: 期間的累積漲跌可能超過單日漲跌幅限制，
+// 前值又只在採用報價後更新，不略過的話真實報價會一直被判定為異常而無法恢復
+// 回傳: 加權前值, 期貨前值, 前值是否可用
+func (d *Data) PriceRefs(now time.Time, holidays string) (float64, float64, bool) {
+	last := max(d.TradingDay, d.NightTradingDay) // 最近一次成功抓取的盤別 (ResetSession 於成功抓取時更新)
+	if last == "" || last < prevTradingDate(now, holidays, loc).Format(time.DateOnly) {
+		return 0, 0, false
 	}
-	if val, ok := m["LastError"]; ok {
-		if v, isStr := val.(string); isStr {
-			d.LastError = v
-		}
+	future := d.LastFutureValue
+	if future == 0 {
+		future = d.LastTWIIValue // 尚無期貨前值時，以加權作為數量級參考
 	}
+	return d.LastTWIIValue, future, true
+}
 
-	return d
+// trackRepeat 比對本次與前次報價，數值或報價時間相同時記錄第一次重複的時間 (since)
+// 回傳: 重複的起始時間是否有變動
+func trackRepeat(q Quote, now time.Time, lastVal *float64, lastTime *time.Time, since *time.Time) bool {
+	prev := *since
+	if q.Value == *lastVal || (!q.Time.IsZero() && q.Time.Equal(*lastTime)) {
+		if since.IsZero() {
+			*since = now
+		}
+	} else {
+		*since = time.Time{}
+	}
+	*lastVal = q.Value
+	*lastTime = q.Time
+	return !since.Equal(prev)
 }
 
-// UpdateDailyHighLow 更新當日最高最低價
-// 邏輯：每天 08:45 (早盤開盤) 重置數據，其餘時間比較並更新極值
-func (d *Data) UpdateDailyHighLow(spotVal, futureVal float64, loc *time.Location) bool {
+// CheckStale 檢查報價是否跨次停滯 (數值或報價時間持續相同超過 after)
+// 以時間而非執行次數判斷，排程間隔 (cron 或常駐模式) 不影響靈敏度
+// checkSpot 為 false 時 (夜盤/盤前/現貨收盤後) 不檢查加權
+// 回傳: 停滯紀錄是否有變動 (需要儲存), 停滯錯誤
+func (d *Data) CheckStale(res ScrapeResult, now time.Time, checkSpot bool, after time.Duration) (bool, error) {
+	if after <= 0 {
+		return false, nil
+	}
 
-	// 🎯 儲存當前價差，用於下次比較
-	d.LastTWIIValue = spotVal
-	d.LastDiffValue = spotVal - futureVal
+	var errs error
+	lastSpot := d.LastTWIIValue // LastTWIIValue 由 UpdateDailyHighLow 維護，這裡只用來比較
+	changed := trackRepeat(res.Future, now, &d.LastFutureValue, &d.FutureQuoteTime, &d.FutureRepeatSince)
+	if !d.FutureRepeatSince.IsZero() && now.Sub(d.FutureRepeatSince) >= after {
+		errs = errors.Join(errs, fmt.Errorf("%w: 台指期自 %s 起未變動 (%.2f, 報價時間 %s)",
+			ErrStaleQuote, d.FutureRepeatSince.In(loc).Format("15:04:05"), res.Future.Value, res.Future.Time.In(loc).Format("15:04:05")))
+	}
 
-	session, isTrading := GetSessionType(loc)
-	if !isTrading {
-		// 休市期間不更新高低點，除非您有特殊的收盤後邏輯
-		return false
+	if checkSpot {
+		if trackRepeat(res.Spot, now, &lastSpot, &d.SpotQuoteTime, &d.SpotRepeatSince) {
+			changed = true
+		}
+		if !d.SpotRepeatSince.IsZero() && now.Sub(d.SpotRepeatSince) >= after {
+			errs = errors.Join(errs, fmt.Errorf("%w: 加權指數自 %s 起未變動 (%.2f, 報價時間 %s)",
+				ErrStaleQuote, d.SpotRepeatSince.In(loc).Format("15:04:05"), res.Spot.Value, res.Spot.Time.In(loc).Format("15:04:05")))
+		}
+	} else if !d.SpotRepeatSince.IsZero() {
+		d.SpotRepeatSince = time.Time{}
+		changed = true
 	}
 
-	shouldSave := false
+	return changed, errs
+}
 
-	// --- 處理早盤 (Spot + Future) ---
-	if session == SessionMorning {
-		// 現貨 (加權)
-		if spotVal > d.SpotHigh {
-			d.SpotHigh = spotVal
-			shouldSave = true
+// ResetSession 新交易日的第一次執行時，保存前一盤的收盤與區間，並清除所屬盤別的高低點
+// 早盤以當天日期判斷；夜盤以開盤日期判斷 (00:00 ~ 05:00 仍屬於前一天開盤的夜盤)
+// 需在比對新高新低 (Build) 之前呼叫
+// lastFuture 為本次報價之前的最後期貨報價 (前一盤的收盤)；CheckStale 會以本次報價覆寫 LastFutureValue
+// 回傳: 是否有重置 (需要儲存)
+func (d *Data) ResetSession(session string, now time.Time, loc *time.Location, lastFuture float64) bool {
+	switch session {
+	case SessionMorning:
+		day := now.In(loc).Format(time.DateOnly)
+		if d.TradingDay == day {
+			return false
 		}
-		// 第一次運行或當日首次運行時初始化
-		if d.SpotLow == 0 || spotVal < d.SpotLow {
-			d.SpotLow = spotVal
-			shouldSave = true
+		d.savePrevSession(SessionNight, d.NightTradingDay != "", lastFuture, d.NightFutureHigh, d.NightFutureLow)
+		d.TradingDay = day
+		d.SpotHigh, d.SpotLow = 0, 0
+		d.FutureHigh, d.FutureLow = 0, 0
+		return true
+	case SessionNight:
+		day := tradingDate(now, loc).Format(time.DateOnly)
+		if d.NightTradingDay == day {
+			return false
 		}
+		// 當天早盤沒有執行時，早盤高低點還是前一個交易日的
+		d.savePrevSession(SessionMorning, d.TradingDay == day, lastFuture, d.FutureHigh, d.FutureLow)
+		d.NightTradingDay = day
+		d.NightFutureHigh, d.NightFutureLow = 0, 0
+		return true
 	}
+	return false
+}
 
-	// --- 處理早盤和夜盤 (Future Only) ---
-	// 注意：夜盤時只會執行到這裡，不會更新 SpotHigh/SpotLow
-
-	// 期貨
-	if futureVal > d.FutureHigh {
-		d.FutureHigh = futureVal
-		shouldSave = true
+// savePrevSession 記錄剛結束的盤別 (該盤沒有紀錄時清除，避免沿用更早的資料)
+func (d *Data) savePrevSession(session string, ok bool, prevClose, high, low float64) {
+	if !ok || high == 0 || low == 0 {
+		d.PrevSession, d.PrevClose, d.PrevHigh, d.PrevLow = "", 0, 0, 0
+		return
 	}
-	// 第一次運行或當日首次運行時初始化
-	if d.FutureLow == 0 || futureVal < d.FutureLow {
-		d.FutureLow = futureVal
-		shouldSave = true
+	d.PrevSession, d.PrevClose, d.PrevHigh, d.PrevLow = session, prevClose, high, low
+}
+
+// PrevSessionInfo 通知訊息附加的前一盤收盤與區間
+func (d *Data) PrevSessionInfo() string {
+	if d.PrevSession == "" {
+		return ""
 	}
+	return fmt.Sprintf("\n%s期貨收盤: %.2f (區間: %.2f ~ %.2f)", SessionName(d.PrevSession), d.PrevClose, d.PrevLow, d.PrevHigh)
+}
 
-	// 額外處理：若為開盤首筆數據，則需初始化所有高低點
-	if d.FutureHigh == 0 && d.FutureLow == 0 && d.SpotHigh == 0 && d.SpotLow == 0 {
-		d.SpotHigh = spotVal
-		d.SpotLow = spotVal
-		d.FutureHigh = futureVal
-		d.FutureLow = futureVal
-		shouldSave = true
+// updateHighLow 以 val 更新高低點 (0 代表本盤尚無紀錄)
+// 回傳: 是否有變動
+func updateHighLow(val float64, high, low *float64) bool {
+	changed := false
+	if val > *high {
+		*high = val
+		changed = true
 	}
+	if *low == 0 || val < *low {
+		*low = val
+		changed = true
+	}
+	return changed
+}
+
+// UpdateDailyHighLow 更新所屬盤別的最高最低價 (換日重置見 ResetSession)
+// session 為本次執行的盤別 (GetSessionType)
+// spotFallback 為 true 時 (盤前) 加權為前次收盤，不列入當日高低點
+func (d *Data) UpdateDailyHighLow(spotVal, futureVal float64, session string, spotFallback bool) bool {
+
+	// 🎯 儲存當前價差，用於下次比較
+	d.LastTWIIValue = spotVal
+	d.LastDiffValue = spotVal - futureVal
+	d.LastFutureValue = futureVal // 換盤時作為前一盤的收盤
 
-	// 確保在夜盤時 Spot 的高低點不會被更新，並且如果是在夜盤時初始化，SpotHigh/Low 會被設為 0
-	// 這裡的邏輯需要您進一步確認，如果夜盤運行時 d.SpotHigh/Low 仍應保持日盤收盤價，那需要在 SaveCurrentData 時進行特殊處理。
-	// 但就「只更新期貨」的要求而言，目前的寫法是正確的：夜盤時，由於 session != SessionMorning，Spot 高低點的判斷會被跳過。
+	switch session {
+	case SessionMorning:
+		shouldSave := updateHighLow(futureVal, &d.FutureHigh, &d.FutureLow)
+		if !spotFallback && updateHighLow(spotVal, &d.SpotHigh, &d.SpotLow) {
+			shouldSave = true
+		}
+		return shouldSave
+	case SessionNight:
+		// 夜盤只有期貨，高低點與早盤分開記錄
+		return updateHighLow(futureVal, &d.NightFutureHigh, &d.NightFutureLow)
+	}
 
-	return shouldSave
+	// 休市期間不更新高低點
+	return false
 }
 
+// 持續失敗時，每隔多久再提醒一次 (以時間計，不受排程間隔影響)
+const errorRemindInterval = time.Hour
+
 // CheckErrorState 檢查錯誤狀態變化
 // 回傳: (是否需要通知, 通知訊息)
-func (d *Data) CheckErrorState(currentErr error) (bool, string) {
+func (d *Data) CheckErrorState(currentErr error, now time.Time) (bool, string) {
 	if currentErr != nil {
 		// 情況 A: 發生錯誤
 		d.LastError = currentErr.Error()
 		d.ErrorCount++
 
+		title := "資料抓取失敗"
+		if errors.Is(currentErr, ErrStaleQuote) {
+			title = "報價資料過期 (停滯)"
+		} else if errors.Is(currentErr, ErrOutlierQuote) {
+			title = "報價數值異常 (疑似抓錯節點)"
+		}
+
 		if d.ErrorCount == 1 {
 			// 1. 正常 -> 失敗 (初次發生)
-			return true, fmt.Sprintf("❌ [系統異常] 資料抓取失敗\n錯誤: %v", currentErr)
+			d.ErrorSince, d.LastErrorAlert = now, now
+			return true, fmt.Sprintf("❌ [系統異常] %s\n錯誤: %v", title, currentErr)
 		} else {
 			// 3. 失敗 -> 失敗 (持續失敗中) -> 靜默 (Log only)
-			// 可選擇每累積 N 次 (例如 12 次 = 1小時) 才提醒一次
-			if d.ErrorCount%12 == 0 {
-				return true, fmt.Sprintf("⚠️ [系統持續異常] 已連續失敗 %d 次\n錯誤: %v", d.ErrorCount, currentErr)
+			// 距離上次通知滿 errorRemindInterval 才提醒一次
+			if d.ErrorSince.IsZero() || d.LastErrorAlert.IsZero() {
+				// 舊版狀態沒有時間紀錄，從現在開始計算
+				d.ErrorSince, d.LastErrorAlert = now, now
+			}
+			if now.Sub(d.LastErrorAlert) >= errorRemindInterval {
+				d.LastErrorAlert = now
+				return true, fmt.Sprintf("⚠️ [系統持續異常] %s, 自 %s 起已連續失敗 %d 次\n錯誤: %v",
+					title, d.ErrorSince.In(loc).Format("01-02 15:04"), d.ErrorCount, currentErr)
 			}
 			return false, "" // 不發送通知
 		}
@@ -179,6 +317,7 @@ func (d *Data) CheckErrorState(currentErr error) (bool, string) {
 			failCount := d.ErrorCount
 			d.ErrorCount = 0
 			d.LastError = ""
+			d.ErrorSince, d.LastErrorAlert = time.Time{}, time.Time{}
 			return true, fmt.Sprintf("✅ [系統恢復] 服務已恢復正常\n(先前連續失敗 %d 次)", failCount)
 		}
 		// 4. 正常 -> 正常 -> 靜默
@@ -186,10 +325,73 @@ func (d *Data) CheckErrorState(currentErr error) (bool, string) {
 	}
 }
 
+// 儲存狀態保留的時間 (Cloud Run 任務期限前預留這段時間寫入狀態)
+const stateSaveTimeout = 5 * time.Second
+
+// persistContext 儲存狀態用的 context: 不隨 ctx 取消，但最多等待 stateSaveTimeout
+// 收到 SIGTERM 或抓取逾時後仍要寫入錯誤狀態，下次執行才知道 ErrorCount
+func persistContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), stateSaveTimeout)
+}
+
+// FirestoreStore 以 Firestore 文件 (TraderAlerts/WatchTwiiDiff) 保存狀態
+type FirestoreStore struct {
+	Project string
+	client  *firestore.Client // 第一次需要時才建立 (非監控時段不必連線)，之後重複使用
+}
+
+func NewFirestoreStore(gcpProject string) *FirestoreStore {
+	return &FirestoreStore{Project: gcpProject}
+}
+
+// Client 取得共用的 Firestore 客戶端
+func (s *FirestoreStore) Client(ctx context.Context) (*firestore.Client, error) {
+	if s.client == nil {
+		client, err := getFirestoreClient(ctx, s.Project)
+		if err != nil {
+			return nil, err
+		}
+		s.client = client
+	}
+	return s.client, nil
+}
+
+func (s *FirestoreStore) Load(ctx context.Context) (*Data, error) {
+	client, err := s.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return GetLastNotifiedData(ctx, client)
+}
+
+func (s *FirestoreStore) Save(ctx context.Context, d *Data) error {
+	client, err := s.Client(ctx)
+	if err != nil {
+		return err
+	}
+	return SaveCurrentData(ctx, client, d)
+}
+
+// SelectorsOverride 讀取爬蟲設定覆寫 (見 GetSelectorsOverride)
+func (s *FirestoreStore) SelectorsOverride(ctx context.Context) (*Selectors, error) {
+	client, err := s.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return GetSelectorsOverride(ctx, client)
+}
+
+// Close 釋放 Firestore 客戶端
+func (s *FirestoreStore) Close() error {
+	if s.client == nil {
+		return nil
+	}
+	return s.client.Close()
+}
+
 // 輔助函式：取得 Firestore 客戶端
-func getFirestoreClient(gcpProject string) (*firestore.Client, error) {
+func getFirestoreClient(ctx context.Context, gcpProject string) (*firestore.Client, error) {
 	// 由於 Cloud Run Jobs 無法讀取GCP_PROJECT, 所以部署時餵入
-	ctx := context.Background()
 	client, err := firestore.NewClient(ctx, gcpProject)
 	if err != nil {
 		return nil, fmt.Errorf("初始化 Firestore 客戶端失敗: %w", err)
@@ -198,52 +400,109 @@ func getFirestoreClient(gcpProject string) (*firestore.Client, error) {
 }
 
 // GetLastNotifiedData 從 Firestore 讀取上次被通知時的價差。
-func GetLastNotifiedData(gcpProject string) (*Data, error) {
-	client, err := getFirestoreClient(gcpProject)
-	if err != nil {
-		return nil, err
-	}
-	defer client.Close()
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// 舊版格式的文件會先升級並寫回，再解析為 Data
+func GetLastNotifiedData(ctx context.Context, client *firestore.Client) (*Data, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	var d = &Data{}
-	doc, err := client.Collection(FirestoreCollection).
-		Doc(FirestoreDocID).
-		Get(ctx)
-
+	ref := client.Collection(FirestoreCollection).Doc(FirestoreDocID)
+	doc, err := ref.Get(ctx)
 	if err != nil {
-		if iterator.Done == err {
+		if status.Code(err) == codes.NotFound {
 			// 第一次運行，文件不存在，返回 0.0
-			return d, nil
+			return &Data{}, nil
 		}
 		return nil, fmt.Errorf("讀取 Firestore 文件失敗: %w", err)
 	}
 
-	return d.Clone(doc.Data()), nil
-}
-
-// SaveCurrentData 將當前的價差儲存到 Firestore。
-func SaveCurrentData(gcpProject string, d *Data) error {
-	client, err := getFirestoreClient(gcpProject)
+	migrated, err := MigrateData(doc.Data())
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if migrated {
+		fmt.Printf("⚠️ 狀態文件為舊版格式，升級為版本 %d...\n", currentSchemaVersion)
+		if err := upgradeDataDoc(ctx, client, ref); err != nil {
+			return nil, fmt.Errorf("升級 Firestore 文件失敗: %w", err)
+		}
+		if doc, err = ref.Get(ctx); err != nil {
+			return nil, fmt.Errorf("讀取 Firestore 文件失敗: %w", err)
+		}
 	}
-	defer client.Close()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	d := &Data{}
+	if err := doc.DataTo(d); err != nil {
+		return nil, fmt.Errorf("解析 Firestore 文件失敗: %w", err)
+	}
+	d.revision = doc.UpdateTime.UnixNano()
+	return d, nil
+}
 
-	d.LastUpdateTime = time.Now()
+// upgradeDataDoc 在交易中將舊版狀態文件升級並寫回 (其他執行已升級時不做任何事)
+func upgradeDataDoc(ctx context.Context, client *firestore.Client, ref *firestore.DocumentRef) error {
+	return client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		m := doc.Data()
+		migrated, err := MigrateData(m)
+		if err != nil || !migrated {
+			return err
+		}
+		return tx.Set(ref, m)
+	})
+}
 
-	_, err = client.Collection(FirestoreCollection).
-		Doc(FirestoreDocID).
-		Set(ctx, d.Map())
+// SaveCurrentData 將當前的價差儲存到 Firestore。
+// 在交易中確認文件的更新時間與讀取時相同，期間被其他執行寫入則回傳 ErrStateConflict
+func SaveCurrentData(ctx context.Context, client *firestore.Client, d *Data) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	ref := client.Collection(FirestoreCollection).Doc(FirestoreDocID)
+	err := client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		var rev int64
+		doc, err := tx.Get(ref)
+		if err == nil {
+			rev = doc.UpdateTime.UnixNano()
+		} else if status.Code(err) != codes.NotFound {
+			return err
+		}
+		if rev != d.revision {
+			return ErrStateConflict
+		}
+		d.SchemaVersion = currentSchemaVersion
+		return tx.Set(ref, d)
+	})
 
+	if errors.Is(err, ErrStateConflict) {
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("寫入 Firestore 失敗: %w", err)
 	}
-	fmt.Printf("✅ 儲存成功, 更新數據%+v\n", d.Map())
+	fmt.Printf("✅ 儲存成功, 更新數據%+v\n", *d)
 	return nil
 }
+
+// GetSelectorsOverride 從 Firestore 讀取爬蟲設定覆寫，文件不存在時回傳 nil
+func GetSelectorsOverride(ctx context.Context, client *firestore.Client) (*Selectors, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	doc, err := client.Collection(FirestoreCollection).
+		Doc(FirestoreSelectorDocID).
+		Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("讀取 Firestore 爬蟲設定失敗: %w", err)
+	}
+
+	var s Selectors
+	if err := doc.DataTo(&s); err != nil {
+		return nil, fmt.Errorf("解析 Firestore 爬蟲設定失敗: %w", err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestData_CheckStale(t *testing.T) {
+	base := time.Date(2025, 12, 3, 10, 0, 0, 0, loc)
+	quote := func(val float64, minutes int) Quote {
+		return Quote{Value: val, Time: base.Add(time.Duration(minutes) * time.Minute)}
+	}
+	// same 期貨數值不變、報價時間照常更新的 n 次執行
+	same := func(n int) []ScrapeResult {
+		runs := make([]ScrapeResult, n)
+		for i := range runs {
+			runs[i] = ScrapeResult{Future: Quote{Value: 20010, Time: base.Add(time.Duration(i) * 30 * time.Second)}}
+		}
+		return runs
+	}
+
+	tests := []struct {
+		name        string
+		checkSpot   bool
+		runs        []ScrapeResult // 依序執行
+		interval    time.Duration  // 執行間隔
+		after       time.Duration
+		wantStale   bool
+		wantErrText string
+	}{
+		{
+			name:      "早盤_數值持續變動_正常",
+			checkSpot: true,
+			interval:  5 * time.Minute,
+			after:     10 * time.Minute,
+			runs: []ScrapeResult{
+				{Spot: quote(20000, 0), Future: quote(20010, 0)},
+				{Spot: quote(20005, 5), Future: quote(20012, 5)},
+				{Spot: quote(20003, 10), Future: quote(20011, 10)},
+				{Spot: quote(20008, 15), Future: quote(20015, 15)},
+			},
+		},
+		{
+			name:      "早盤_期貨持續相同_停滯",
+			checkSpot: true,
+			interval:  5 * time.Minute,
+			after:     10 * time.Minute,
+			runs: []ScrapeResult{
+				{Spot: quote(20000, 0), Future: quote(20010, 0)},
+				{Spot: quote(20005, 5), Future: quote(20010, 5)},
+				{Spot: quote(20003, 10), Future: quote(20010, 10)},
+				{Spot: quote(20008, 15), Future: quote(20010, 15)},
+			},
+			wantStale:   true,
+			wantErrText: "台指期自 10:05:00 起未變動",
+		},
+		{
+			name:     "常駐模式_間隔較短_未達停滯時間",
+			interval: 30 * time.Second,
+			after:    10 * time.Minute,
+			runs:     same(12), // 相同 5 分半鐘
+		},
+		{
+			name:        "常駐模式_間隔較短_超過停滯時間",
+			interval:    30 * time.Second,
+			after:       10 * time.Minute,
+			runs:        same(22), // 第二次執行 (10:00:30) 開始重複，10:10:30 達到 10 分鐘
+			wantStale:   true,
+			wantErrText: "台指期自 10:00:30 起未變動",
+		},
+		{
+			name:      "早盤_報價時間未更新_停滯",
+			checkSpot: true,
+			interval:  5 * time.Minute,
+			after:     5 * time.Minute,
+			runs: []ScrapeResult{
+				{Spot: quote(20000, 0), Future: quote(20010, 0)},
+				{Spot: quote(20001, 0), Future: quote(20011, 5)},
+				{Spot: quote(20002, 0), Future: quote(20012, 10)},
+			},
+			wantStale:   true,
+			wantErrText: "加權指數自 10:05:00 起未變動",
+		},
+		{
+			name:     "夜盤_加權不變_不檢查加權",
+			interval: 5 * time.Minute,
+			after:    5 * time.Minute,
+			runs: []ScrapeResult{
+				{Spot: quote(20000, 0), Future: quote(20010, 0)},
+				{Spot: quote(20000, 0), Future: quote(20015, 5)},
+				{Spot: quote(20000, 0), Future: quote(20020, 10)},
+			},
+		},
+		{
+			name:     "停滯後恢復_重新計時",
+			interval: 5 * time.Minute,
+			after:    10 * time.Minute,
+			runs: []ScrapeResult{
+				{Future: quote(20010, 0)},
+				{Future: quote(20010, 5)},
+				{Future: quote(20010, 10)},
+				{Future: quote(20020, 15)},
+				{Future: quote(20020, 20)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Data{}
+			var err error
+			for i, res := range tt.runs {
+				_, err = d.CheckStale(res, base.Add(time.Duration(i)*tt.interval), tt.checkSpot, tt.after)
+				// 模擬 UpdateDailyHighLow 保存本次加權
+				d.LastTWIIValue = res.Spot.Value
+			}
+
+			if gotStale := errors.Is(err, ErrStaleQuote); gotStale != tt.wantStale {
+				t.Fatalf("CheckStale() stale = %v, want %v (err: %v)", gotStale, tt.wantStale, err)
+			}
+			if tt.wantStale && !strings.Contains(err.Error(), tt.wantErrText) {
+				t.Errorf("CheckStale() error = %v, want substring %v", err, tt.wantErrText)
+			}
+		})
+	}
+}
+
+func TestData_CheckErrorState_Stale(t *testing.T) {
+	d := &Data{}
+	notify, msg := d.CheckErrorState(errors.Join(ErrStaleQuote, errors.New("台指期自 10:05:00 起未變動")), time.Now())
+	if !notify || !strings.Contains(msg, "報價資料過期") {
+		t.Errorf("CheckErrorState() = (%v, %v), want stale data alert", notify, msg)
+	}
+}
+
+func TestData_CheckErrorState_Remind(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, loc)
+	errDown := errors.New("連線逾時")
+
+	tests := []struct {
+		name      string
+		interval  time.Duration // 執行間隔
+		wantAlert int           // 兩小時內的異常通知數 (初次 + 每小時提醒)
+	}{
+		{name: "排程_每5分鐘", interval: 5 * time.Minute, wantAlert: 3},
+		{name: "常駐模式_每30秒", interval: 30 * time.Second, wantAlert: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Data{}
+			var alerts []string
+			for now := start; !now.After(start.Add(2 * time.Hour)); now = now.Add(tt.interval) {
+				if notify, msg := d.CheckErrorState(errDown, now); notify {
+					alerts = append(alerts, msg)
+				}
+			}
+			if len(alerts) != tt.wantAlert {
+				t.Fatalf("CheckErrorState() alerts = %q, want %d", alerts, tt.wantAlert)
+			}
+			if !strings.Contains(alerts[1], "自 10-16 09:00 起已連續失敗") {
+				t.Errorf("CheckErrorState() reminder = %q, want failure start time", alerts[1])
+			}
+
+			notify, msg := d.CheckErrorState(nil, start.Add(3*time.Hour))
+			if !notify || !strings.Contains(msg, "系統恢復") || !d.ErrorSince.IsZero() || !d.LastErrorAlert.IsZero() {
+				t.Errorf("CheckErrorState(nil) = (%v, %q), want recovery with cleared times", notify, msg)
+			}
+		})
+	}
+}
+
+func TestPersistContext_CancelledScrape(t *testing.T) {
+	// 抓取途中收到 SIGTERM (ctx 取消)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	src := &fakeSource{name: "slow", spot: 20000, future: 20010, spotDelay: time.Second, futureDelay: time.Second}
+	_, scrapeErr := ScrapeData(ctx, []QuoteSource{src}, ScrapePolicy{})
+	if !errors.Is(scrapeErr, context.Canceled) {
+		t.Fatalf("ScrapeData() error = %v, want context.Canceled", scrapeErr)
+	}
+
+	d := &Data{ErrorCount: 2}
+	d.CheckErrorState(scrapeErr, time.Now())
+	if d.ErrorCount != 3 || !strings.Contains(d.LastError, "context canceled") {
+		t.Errorf("CheckErrorState() = (%d, %q), want error state recorded", d.ErrorCount, d.LastError)
+	}
+
+	// 儲存用的 context 不隨 ctx 取消，錯誤狀態仍能寫入
+	saveCtx, cancelSave := persistContext(ctx)
+	defer cancelSave()
+	if err := saveCtx.Err(); err != nil {
+		t.Fatalf("persistContext() err = %v, want usable context after cancel", err)
+	}
+	deadline, ok := saveCtx.Deadline()
+	if !ok || time.Until(deadline) > stateSaveTimeout {
+		t.Errorf("persistContext() deadline = %v, want within %v", deadline, stateSaveTimeout)
+	}
+}
+
+func TestData_ResetSession(t *testing.T) {
+	tests := []struct {
+		name       string
+		session    string
+		now        time.Time
+		tradingDay string // 早盤高低點所屬的交易日
+		wantReset  bool
+		wantDay    string // 重置後早盤的交易日
+		wantNight  string // 重置後夜盤的開盤日
+		wantPrev   string // 重置後的前一盤盤別
+		wantRange  [2]float64
+	}{
+		{
+			name:       "早盤_同一天_不重置",
+			session:    SessionMorning,
+			now:        time.Date(2026, 10, 15, 10, 0, 0, 0, loc),
+			tradingDay: "2026-10-15",
+			wantDay:    "2026-10-15",
+			wantNight:  "2026-10-14",
+		},
+		{
+			name:       "早盤_新交易日_重置並記錄夜盤",
+			session:    SessionMorning,
+			now:        time.Date(2026, 10, 16, 8, 45, 0, 0, loc),
+			tradingDay: "2026-10-15",
+			wantReset:  true,
+			wantDay:    "2026-10-16",
+			wantNight:  "2026-10-14",
+			wantPrev:   SessionNight,
+			wantRange:  [2]float64{26950, 27150},
+		},
+		{
+			name:       "夜盤_開盤_重置並記錄早盤",
+			session:    SessionNight,
+			now:        time.Date(2026, 10, 15, 15, 0, 0, 0, loc),
+			tradingDay: "2026-10-15",
+			wantReset:  true,
+			wantDay:    "2026-10-15",
+			wantNight:  "2026-10-15",
+			wantPrev:   SessionMorning,
+			wantRange:  [2]float64{26880, 27120},
+		},
+		{
+			name:       "夜盤_當天早盤未執行_不記錄前一盤",
+			session:    SessionNight,
+			now:        time.Date(2026, 10, 16, 15, 0, 0, 0, loc),
+			tradingDay: "2026-10-15",
+			wantReset:  true,
+			wantDay:    "2026-10-15",
+			wantNight:  "2026-10-16",
+		},
+		{
+			name:       "夜盤_跨午夜_仍屬前一天開盤的夜盤",
+			session:    SessionNight,
+			now:        time.Date(2026, 10, 15, 2, 0, 0, 0, loc),
+			tradingDay: "2026-10-15",
+			wantDay:    "2026-10-15",
+			wantNight:  "2026-10-14",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Data{
+				LastFutureValue: 27000,
+				TradingDay:      tt.tradingDay,
+				SpotHigh:        27100, SpotLow: 26900,
+				FutureHigh: 27120, FutureLow: 26880,
+				NightTradingDay: "2026-10-14",
+				NightFutureHigh: 27150, NightFutureLow: 26950,
+			}
+
+			if got := d.ResetSession(tt.session, tt.now, loc, d.LastFutureValue); got != tt.wantReset {
+				t.Fatalf("ResetSession() = %v, want %v", got, tt.wantReset)
+			}
+			if d.TradingDay != tt.wantDay || d.NightTradingDay != tt.wantNight {
+				t.Errorf("ResetSession() days = (%s, %s), want (%s, %s)", d.TradingDay, d.NightTradingDay, tt.wantDay, tt.wantNight)
+			}
+
+			dayReset := d.SpotHigh == 0 && d.SpotLow == 0 && d.FutureHigh == 0 && d.FutureLow == 0
+			nightReset := d.NightFutureHigh == 0 && d.NightFutureLow == 0
+			if want := tt.wantReset && tt.session == SessionMorning; dayReset != want {
+				t.Errorf("ResetSession() day high/low cleared = %v, want %v", dayReset, want)
+			}
+			if want := tt.wantReset && tt.session == SessionNight; nightReset != want {
+				t.Errorf("ResetSession() night high/low cleared = %v, want %v", nightReset, want)
+			}
+
+			if d.PrevSession != tt.wantPrev {
+				t.Fatalf("ResetSession() PrevSession = %q, want %q", d.PrevSession, tt.wantPrev)
+			}
+			if tt.wantPrev != "" && (d.PrevClose != 27000 || d.PrevLow != tt.wantRange[0] || d.PrevHigh != tt.wantRange[1]) {
+				t.Errorf("ResetSession() prev close/range = %.2f (%.2f ~ %.2f), want 27000.00 (%.2f ~ %.2f)",
+					d.PrevClose, d.PrevLow, d.PrevHigh, tt.wantRange[0], tt.wantRange[1])
+			}
+		})
+	}
+}
+
+func TestData_PriceRefs(t *testing.T) {
+	at := func(month, day, hour int) time.Time {
+		return time.Date(2026, time.Month(month), day, hour, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		holidays string
+		day      string // 最近一次早盤
+		nightDay string // 最近一次夜盤
+		wantOK   bool
+	}{
+		{name: "同一交易日", now: at(10, 16, 10), day: "2026-10-16", nightDay: "2026-10-15", wantOK: true},
+		{name: "前一個交易日的夜盤", now: at(10, 16, 9), day: "2026-10-15", nightDay: "2026-10-15", wantOK: true},
+		{name: "夜盤凌晨屬於前一天", now: at(10, 17, 2), day: "2026-10-16", nightDay: "2026-10-15", wantOK: true},
+		{name: "週一_前值為週五", now: at(10, 19, 9), day: "2026-10-16", nightDay: "2026-10-16", wantOK: true},
+		{name: "連假後_略過休市日", now: at(10, 27, 9), holidays: "2026-10-26", day: "2026-10-23", nightDay: "2026-10-23", wantOK: true},
+		{name: "停機數日_不檢查偏離", now: at(10, 16, 10), day: "2026-10-13", nightDay: "2026-10-13"},
+		{name: "沒有紀錄", now: at(10, 16, 10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Data{LastTWIIValue: 27000, LastFutureValue: 27010, TradingDay: tt.day, NightTradingDay: tt.nightDay}
+			spot, future, ok := d.PriceRefs(tt.now, tt.holidays)
+			if ok != tt.wantOK {
+				t.Fatalf("PriceRefs() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (spot != 27000 || future != 27010) {
+				t.Errorf("PriceRefs() = (%.2f, %.2f), want (27000.00, 27010.00)", spot, future)
+			}
+			if !ok && (spot != 0 || future != 0) {
+				t.Errorf("PriceRefs() = (%.2f, %.2f), want no reference", spot, future)
+			}
+		})
+	}
+}
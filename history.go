@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// Firestore 歷史紀錄: WatchTwiiHistory/{交易日}/Ticks/{時間}
+const (
+	FirestoreHistoryCollection = "WatchTwiiHistory"
+	FirestoreTickCollection    = "Ticks"
+)
+
+// Tick 單次成功抓取的紀錄
+type Tick struct {
+	Time         time.Time `firestore:"Time" json:"time"`
+	Session      string    `firestore:"Session" json:"session"`
+	Spot         float64   `firestore:"Spot" json:"spot"`
+	Future       float64   `firestore:"Future" json:"future"`
+	Diff         float64   `firestore:"Diff" json:"diff"` // 加權 - 期貨
+	SpotSource   string    `firestore:"SpotSource" json:"spot_source"`
+	FutureSource string    `firestore:"FutureSource" json:"future_source"`
+	Contract     string    `firestore:"Contract" json:"contract"`
+	Alerted      bool      `firestore:"Alerted" json:"alerted"`          // 是否發送行情通知
+	AlertReason  string    `firestore:"AlertReason" json:"alert_reason"` // 通知內容 (不含來源說明)
+}
+
+// TradingDay 紀錄所屬的交易日 (夜盤 00:00 ~ 05:00 屬於前一天開盤的夜盤)
+func (t Tick) TradingDay() string {
+	return tradingDate(t.Time, loc).Format(time.DateOnly)
+}
+
+// HistoryStore 歷史紀錄儲存 (每個交易日一組，供報表與其他工具查詢)
+type HistoryStore interface {
+	Append(ctx context.Context, t Tick) error
+	// Query 回傳時間在 [from, to) 之間的紀錄，依時間排序
+	Query(ctx context.Context, from, to time.Time) ([]Tick, error)
+	Close() error
+}
+
+// historyStores 可用的歷史紀錄儲存 (HISTORY_BACKEND -> 建構函式)
+// state 為同時使用的狀態儲存 (可為 nil)，Firestore 以此共用客戶端
+var historyStores = map[string]func(cfg *Config, state StateStore) HistoryStore{
+	"firestore": func(cfg *Config, state StateStore) HistoryStore {
+		if store, ok := state.(*FirestoreStore); ok {
+			return NewFirestoreHistory(store, true)
+		}
+		return NewFirestoreHistory(NewFirestoreStore(cfg.GCPProject), false)
+	},
+	"file":   func(cfg *Config, state StateStore) HistoryStore { return NewFileHistory(cfg.HistoryDir) },
+	"memory": func(cfg *Config, state StateStore) HistoryStore { return NewMemoryHistory() },
+}
+
+// NewHistoryStore 依 HISTORY_BACKEND 建立歷史紀錄儲存 (none 時回傳 nil，代表不記錄)
+// state 為 Firestore 時共用其客戶端，不另外建立連線
+func NewHistoryStore(cfg *Config, state StateStore) (HistoryStore, error) {
+	backend := cfg.HistoryStoreBackend()
+	if backend == "none" {
+		return nil, nil
+	}
+	factory, ok := historyStores[backend]
+	if !ok {
+		return nil, fmt.Errorf("未知的歷史紀錄儲存: %s", backend)
+	}
+	return factory(cfg, state), nil
+}
+
+// tradingDays 涵蓋 [from, to) 的交易日
+func tradingDays(from, to time.Time) []string {
+	var days []string
+	last := tradingDate(to, loc)
+	for day := tradingDate(from, loc); !day.After(last); day = day.AddDate(0, 0, 1) {
+		days = append(days, day.Format(time.DateOnly))
+	}
+	return days
+}
+
+// inRange 紀錄時間是否在 [from, to) 之間
+func inRange(t Tick, from, to time.Time) bool {
+	return !t.Time.Before(from) && t.Time.Before(to)
+}
+
+// FirestoreHistory 以 Firestore 子集合保存歷史紀錄 (每個交易日一個文件，底下的 Ticks 為當日紀錄)
+type FirestoreHistory struct {
+	store  *FirestoreStore // 客戶端的建立與釋放
+	shared bool            // 客戶端與狀態儲存共用 (由狀態儲存釋放)
+}
+
+// NewFirestoreHistory 以 store 的客戶端存取歷史紀錄
+// shared 為 true 時 store 為狀態儲存，Close 不釋放客戶端
+func NewFirestoreHistory(store *FirestoreStore, shared bool) *FirestoreHistory {
+	return &FirestoreHistory{store: store, shared: shared}
+}
+
+func (h *FirestoreHistory) ticks(client *firestore.Client, day string) *firestore.CollectionRef {
+	return client.Collection(FirestoreHistoryCollection).Doc(day).Collection(FirestoreTickCollection)
+}
+
+func (h *FirestoreHistory) Append(ctx context.Context, t Tick) error {
+	client, err := h.store.Client(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// 以時間作為文件 ID，重複寫入同一筆紀錄時不會產生多筆
+	id := strconv.FormatInt(t.Time.UnixNano(), 10)
+	if _, err := h.ticks(client, t.TradingDay()).Doc(id).Set(ctx, t); err != nil {
+		return fmt.Errorf("寫入 Firestore 歷史紀錄失敗: %w", err)
+	}
+	return nil
+}
+
+func (h *FirestoreHistory) Query(ctx context.Context, from, to time.Time) ([]Tick, error) {
+	client, err := h.store.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Tick
+	for _, day := range tradingDays(from, to) {
+		iter := h.ticks(client, day).
+			Where("Time", ">=", from).
+			Where("Time", "<", to).
+			OrderBy("Time", firestore.Asc).
+			Documents(ctx)
+		for {
+			doc, err := iter.Next()
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			if err != nil {
+				iter.Stop()
+				return nil, fmt.Errorf("讀取 Firestore 歷史紀錄失敗 (%s): %w", day, err)
+			}
+			var t Tick
+			if err := doc.DataTo(&t); err != nil {
+				iter.Stop()
+				return nil, fmt.Errorf("解析 Firestore 歷史紀錄失敗 (%s): %w", doc.Ref.ID, err)
+			}
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (h *FirestoreHistory) Close() error {
+	if h.shared {
+		return nil
+	}
+	return h.store.Close()
+}
+
+// FileHistory 以本機 JSON Lines 檔保存歷史紀錄 (每個交易日一個檔案: {Dir}/2006-01-02.jsonl)
+type FileHistory struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+func NewFileHistory(dir string) *FileHistory {
+	return &FileHistory{Dir: dir}
+}
+
+func (h *FileHistory) path(day string) string {
+	return filepath.Join(h.Dir, day+".jsonl")
+}
+
+func (h *FileHistory) Append(ctx context.Context, t Tick) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	line, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(h.Dir, 0o755); err != nil {
+		return fmt.Errorf("建立歷史紀錄目錄失敗: %w", err)
+	}
+	file, err := os.OpenFile(h.path(t.TradingDay()), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("開啟歷史紀錄檔失敗: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("寫入歷史紀錄檔失敗: %w", err)
+	}
+	return file.Close()
+}
+
+func (h *FileHistory) Query(ctx context.Context, from, to time.Time) ([]Tick, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var result []Tick
+	for _, day := range tradingDays(from, to) {
+		ticks, err := h.read(day)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range ticks {
+			if inRange(t, from, to) {
+				result = append(result, t)
+			}
+		}
+	}
+	// 多個程序同時寫入時順序可能交錯
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Time.Before(result[j].Time) })
+	return result, nil
+}
+
+// read 讀取單日的紀錄 (檔案不存在代表當天沒有紀錄)
+func (h *FileHistory) read(day string) ([]Tick, error) {
+	file, err := os.Open(h.path(day))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("讀取歷史紀錄檔失敗: %w", err)
+	}
+	defer file.Close()
+
+	var ticks []Tick
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var t Tick
+		if err := json.Unmarshal(scanner.Bytes(), &t); err != nil {
+			return nil, fmt.Errorf("解析歷史紀錄檔失敗 (%s 第 %d 行): %w", day, n, err)
+		}
+		ticks = append(ticks, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("讀取歷史紀錄檔失敗: %w", err)
+	}
+	return ticks, nil
+}
+
+func (h *FileHistory) Close() error {
+	return nil
+}
+
+// MemoryHistory 記憶體中的歷史紀錄 (測試用，程式結束即遺失)
+type MemoryHistory struct {
+	mu    sync.Mutex
+	ticks []Tick
+}
+
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{}
+}
+
+func (h *MemoryHistory) Append(ctx context.Context, t Tick) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ticks = append(h.ticks, t)
+	return nil
+}
+
+func (h *MemoryHistory) Query(ctx context.Context, from, to time.Time) ([]Tick, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var result []Tick
+	for _, t := range h.ticks {
+		if inRange(t, from, to) {
+			result = append(result, t)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Time.Before(result[j].Time) })
+	return result, nil
+}
+
+func (h *MemoryHistory) Close() error {
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryStore_Query(t *testing.T) {
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2026, 10, day, hour, min, 0, 0, loc)
+	}
+	// 依寫入順序，夜盤 01:00 的紀錄屬於前一天開盤的夜盤
+	ticks := []Tick{
+		{Time: at(15, 10, 0), Session: SessionMorning, Spot: 27000, Future: 26950, Diff: 50},
+		{Time: at(15, 20, 0), Session: SessionNight, Spot: 27000, Future: 27020, Diff: -20, Alerted: true, AlertReason: "夜盤新高"},
+		{Time: at(16, 1, 0), Session: SessionNight, Spot: 27000, Future: 27040, Diff: -40},
+		{Time: at(16, 9, 30), Session: SessionMorning, Spot: 27050, Future: 27030, Diff: 20},
+		{Time: at(16, 9, 0), Session: SessionMorning, Spot: 27045, Future: 27025, Diff: 20}, // 較晚寫入的較早紀錄
+	}
+
+	stores := []struct {
+		name  string
+		store HistoryStore
+	}{
+		{"file", NewFileHistory(filepath.Join(t.TempDir(), "history"))},
+		{"memory", NewMemoryHistory()},
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []time.Time
+	}{
+		{
+			name: "單一交易日",
+			from: at(15, 0, 0), to: at(15, 15, 0),
+			want: []time.Time{at(15, 10, 0)},
+		},
+		{
+			name: "跨午夜的夜盤",
+			from: at(15, 15, 0), to: at(16, 5, 0),
+			want: []time.Time{at(15, 20, 0), at(16, 1, 0)},
+		},
+		{
+			name: "多個交易日_依時間排序",
+			from: at(15, 0, 0), to: at(17, 0, 0),
+			want: []time.Time{at(15, 10, 0), at(15, 20, 0), at(16, 1, 0), at(16, 9, 0), at(16, 9, 30)},
+		},
+		{
+			name: "結束時間不包含",
+			from: at(16, 9, 0), to: at(16, 9, 30),
+			want: []time.Time{at(16, 9, 0)},
+		},
+		{
+			name: "沒有紀錄的日期",
+			from: at(20, 0, 0), to: at(21, 0, 0),
+		},
+	}
+
+	for _, st := range stores {
+		ctx := context.Background()
+		for _, tick := range ticks {
+			if err := st.store.Append(ctx, tick); err != nil {
+				t.Fatalf("%s: Append() unexpected error: %v", st.name, err)
+			}
+		}
+
+		for _, tt := range tests {
+			t.Run(st.name+"_"+tt.name, func(t *testing.T) {
+				got, err := st.store.Query(ctx, tt.from, tt.to)
+				if err != nil {
+					t.Fatalf("Query() unexpected error: %v", err)
+				}
+				if len(got) != len(tt.want) {
+					t.Fatalf("Query() = %d ticks, want %d", len(got), len(tt.want))
+				}
+				for i, want := range tt.want {
+					if !got[i].Time.Equal(want) {
+						t.Errorf("Query()[%d].Time = %v, want %v", i, got[i].Time, want)
+					}
+				}
+			})
+		}
+
+		t.Run(st.name+"_保留通知原因", func(t *testing.T) {
+			got, _ := st.store.Query(ctx, at(15, 20, 0), at(15, 21, 0))
+			if len(got) != 1 || !got[0].Alerted || got[0].AlertReason != "夜盤新高" || got[0].Future != 27020 || got[0].Session != SessionNight {
+				t.Errorf("Query() = %+v, want night tick with alert reason", got)
+			}
+		})
+	}
+}
+
+func TestNewHistoryStore(t *testing.T) {
+	state := NewFirestoreStore("test-project")
+
+	tests := []struct {
+		name       string
+		cfg        *Config
+		state      StateStore
+		wantNil    bool
+		wantShared bool // Firestore 是否共用狀態儲存的客戶端
+		wantErr    bool
+	}{
+		{name: "預設與狀態儲存相同_共用客戶端", cfg: &Config{StateBackend: "firestore"}, state: state, wantShared: true},
+		{name: "狀態儲存為本機檔_另建客戶端", cfg: &Config{StateBackend: "file", HistoryBackend: "firestore"}, state: NewFileStore("state.json")},
+		{name: "重播工具沒有狀態儲存", cfg: &Config{HistoryBackend: "firestore"}},
+		{name: "不記錄", cfg: &Config{StateBackend: "firestore", HistoryBackend: "none"}, state: state, wantNil: true},
+		{name: "未知的儲存", cfg: &Config{HistoryBackend: "redis"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewHistoryStore(tt.cfg, tt.state)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewHistoryStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr || tt.wantNil {
+				if got != nil {
+					t.Errorf("NewHistoryStore() = %T, want nil", got)
+				}
+				return
+			}
+			h, ok := got.(*FirestoreHistory)
+			if !ok {
+				t.Fatalf("NewHistoryStore() = %T, want *FirestoreHistory", got)
+			}
+			if shared := h.store == state; shared != tt.wantShared || h.shared != tt.wantShared {
+				t.Errorf("NewHistoryStore() shared = (%v, %v), want %v", shared, h.shared, tt.wantShared)
+			}
+		})
+	}
+}
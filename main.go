@@ -1,49 +1,44 @@
 package main
 
 import (
-	"errors"
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/colindev/osenv"
 	"github.com/joho/godotenv"
 )
 
-// --- 設定區 (建議透過環境變數注入) ---
+// --- 預設爬蟲設定 (可透過 SELECTORS_FILE / 環境變數 / Firestore 覆寫，見 selectors.go) ---
 const (
 
 	// 這裡填入您實際要抓取的網站與 XPath
 	// 範例：Yahoo 股市 (僅供參考，XPath 需隨網頁結構更新)
-	SpotURL   = "https://tw.stock.yahoo.com/quote/%5ETWII" // 加權指數
-	SpotXPath = "//*[@id='main-0-QuoteHeader-Proxy']/div/div[2]/div[1]/div/span[1]"
+	DefaultSpotURL   = "https://tw.stock.yahoo.com/quote/%5ETWII" // 加權指數
+	DefaultSpotXPath = "//*[@id='main-0-QuoteHeader-Proxy']/div/div[2]/div[1]/div/span[1]"
 
-	FutureURL   = "https://tw.stock.yahoo.com/future/futures.html?fumr=futurefull" // 台指近一 (需確認網址是否為連續月)
-	FutureXPath = "/html/body/div[1]/div/div/div/div/div[3]/div[1]/div/div/div[2]/div[3]/div[2]/div/div/ul/li[2]/div/div[4]/span"
+	DefaultFutureURL   = "https://tw.stock.yahoo.com/future/futures.html?fumr=futurefull" // 台指近一 (需確認網址是否為連續月)
+	DefaultFutureXPath = "/html/body/div[1]/div/div/div/div/div[3]/div[1]/div/div/div[2]/div[3]/div[2]/div/div/ul/li[2]/div/div[4]/span"
 )
 
-func ScrapeData() (spotVal float64, futureVal float64, errs error) {
-
-	// 取得台指期
-	rawFuture, err := FetchValueString(FutureURL, FutureXPath)
-	if err != nil {
-		err = fmt.Errorf("抓取台指期失敗: %w", err)
-	} else if futureVal, err = ParseToFloat(rawFuture); err != nil {
-		err = fmt.Errorf("解析台指期失敗: %w", err)
+// 頁面改版時的候選 XPath (由 probe 指令檢查哪些仍然有效)
+var (
+	DefaultSpotFallbackXPaths = []string{
+		"//*[@id='main-0-QuoteHeader-Proxy']//span[contains(@class,'Fz(32px)')]",
+		"//div[contains(@class,'quote-header')]//span[contains(@class,'Fz(32px)')]",
+		"//*[@id='main-0-QuoteHeader-Proxy']//div[contains(@class,'D(f)')]/span[1]",
 	}
-
-	// 取得加權指數
-	rawSpot, errSpot := FetchValueString(SpotURL, SpotXPath)
-	if errSpot != nil {
-		errs = errors.Join(errs, fmt.Errorf("抓取加權指數失敗: %w", errSpot))
-	} else if spotVal, errSpot = ParseToFloat(rawSpot); errSpot != nil {
-		errs = errors.Join(errs, fmt.Errorf("解析加權指數失敗: %w", errSpot))
+	DefaultFutureFallbackXPaths = []string{
+		"//ul//li[contains(., '台指期') and contains(., '近一')]//div[4]/span",
+		"//ul//li[2]//div[contains(@class,'Fxg(1)')][4]/span",
+		"//li[.//a[contains(@href,'WTX')]]//div[4]/span",
 	}
-
-	return
-}
+)
 
 // 環境變數中的 Key
 var (
@@ -62,11 +57,79 @@ type Config struct {
 	TelegramChatIDs string `env:"TELEGRAM_CHAT_IDS"`
 
 	// 監控閾值
-	Threshold        float64 `env:"THRESHOLD"`
-	ThresholdChanged float64 `env:"THRESHOLD_CHANGED"`
+	Threshold        float64 `env:"THRESHOLD,70"`
+	ThresholdChanged float64 `env:"THRESHOLD_CHANGED,35"`
 
 	// 特殊休市日 (格式: 2026-01-01,2026-01-02)
 	SpecialDates string `env:"SPECIAL_DATES"`
+
+	// 報價來源 (依序嘗試，格式: twse,taifex,yahoo)
+	QuoteSources   []string `env:"QUOTE_SOURCES,yahoo"`
+	QuoteQuorum    int      `env:"QUOTE_QUORUM,1"`    // 需幾個來源報價一致才採用
+	QuoteTolerance float64  `env:"QUOTE_TOLERANCE,5"` // 來源間可接受的差距 (點)
+
+	// 報價停滯檢查
+	MaxQuoteAge time.Duration `env:"MAX_QUOTE_AGE,20m"` // 報價時間最多可落後多久
+	StaleAfter  time.Duration `env:"STALE_AFTER,30m"`   // 交易中數值持續相同多久視為停滯 (0 代表不檢查)
+
+	// 報價合理範圍
+	PriceMin          float64 `env:"PRICE_MIN,1000"`          // 數量級下限
+	PriceMax          float64 `env:"PRICE_MAX,100000"`        // 數量級上限
+	PriceMaxDeviation float64 `env:"PRICE_MAX_DEVIATION,0.1"` // 與前值的最大偏離比例 (台股漲跌幅限制 10%)
+
+	// 爬蟲頁面與 XPath (由 LoadSelectors 載入)
+	Selectors Selectors
+
+	// HTTP 抓取
+	HTTPTimeout    time.Duration `env:"HTTP_TIMEOUT,10s"`   // 每次請求的期限
+	HTTPRetries    int           `env:"HTTP_RETRIES,2"`     // 失敗後重試次數
+	HTTPRetryBase  time.Duration `env:"HTTP_RETRY_BASE,1s"` // 第一次重試前的等待時間 (之後加倍並加上抖動)
+	HTTPRetryMax   time.Duration `env:"HTTP_RETRY_MAX,5s"`  // 重試等待時間上限
+	ScrapeProxy    string        `env:"SCRAPE_PROXY"`       // 爬蟲使用的 HTTP Proxy (未設定時沿用 HTTP(S)_PROXY)
+	UserAgent      string        // HTTP_USER_AGENT
+	AcceptLanguage string        // HTTP_ACCEPT_LANGUAGE
+
+	// 同時抓取加權與期貨
+	ScrapeDeadline time.Duration `env:"SCRAPE_DEADLINE,30s"` // 單次抓取 (含所有來源與重試) 的期限
+	MaxFetchSkew   time.Duration `env:"MAX_FETCH_SKEW,5s"`   // 加權與期貨取得時間最多可相差多久
+
+	// Cloud Run 任務期限 (需與部署的 --task-timeout 一致，0 代表不限制)
+	// 常駐模式下則是每次檢查的期限
+	TaskTimeout time.Duration `env:"TASK_TIMEOUT,60s"`
+
+	// 常駐模式 (--daemon) 盤中的檢查間隔
+	PollInterval time.Duration `env:"POLL_INTERVAL,30s"`
+
+	// 狀態儲存 (firestore: Firestore 文件, file: 本機 JSON 檔, memory: 記憶體，程式結束即遺失)
+	StateBackend string `env:"STATE_BACKEND,firestore"`
+	StateFile    string `env:"STATE_FILE,watchtwii-state.json"` // file 模式的檔案路徑
+
+	// 歷史紀錄 (每次成功抓取的報價)，未設定時與 STATE_BACKEND 相同，none 代表不記錄
+	HistoryBackend string `env:"HISTORY_BACKEND"`
+	HistoryDir     string `env:"HISTORY_DIR,watchtwii-history"` // file 模式的目錄
+
+	// 盤前簡報 (08:30) 附上的美股指數 (yahoo: Yahoo Finance, none: 不附美股)
+	USIndexSource  string   `env:"US_INDEX_SOURCE,yahoo"`
+	USIndexSymbols []string `env:"US_INDEX_SYMBOLS,^DJI,^GSPC,^IXIC,^SOX"`
+}
+
+// HistoryStoreBackend 實際使用的歷史紀錄儲存
+func (c *Config) HistoryStoreBackend() string {
+	if c.HistoryBackend == "" {
+		return c.StateBackend
+	}
+	return c.HistoryBackend
+}
+
+// ScrapePolicy 報價採用規則
+func (c *Config) ScrapePolicy() ScrapePolicy {
+	return ScrapePolicy{
+		Required:  c.QuoteQuorum,
+		Tolerance: c.QuoteTolerance,
+		MaxAge:    c.MaxQuoteAge,
+		Bounds:    PriceBounds{Min: c.PriceMin, Max: c.PriceMax, MaxDeviation: c.PriceMaxDeviation},
+		MaxSkew:   c.MaxFetchSkew,
+	}
 }
 
 // LoadConfig 負責載入並驗證設定，若缺少必要欄位則直接回傳 error (Fail-Fast)
@@ -77,11 +140,9 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("載入環境變數檔案失敗: %w", err)
 	}
 
-	cfg := &Config{}
-
-	// 使用您的 osenv 庫載入設定
-	if err := osenv.LoadTo(cfg); err != nil {
-		return nil, fmt.Errorf("載入環境變數失敗: %w", err)
+	cfg, err := loadEnvConfig()
+	if err != nil {
+		return nil, err
 	}
 
 	// 這裡進行 Fail-Fast 的強驗證
@@ -91,6 +152,55 @@ func LoadConfig() (*Config, error) {
 	if cfg.TelegramChatIDs == "" {
 		return nil, fmt.Errorf("缺少必填環境變數: TELEGRAM_CHAT_IDS")
 	}
+	sources, err := NewSources(cfg, nil) // 只檢查來源設定，不會抓取
+	if err != nil {
+		return nil, fmt.Errorf("QUOTE_SOURCES 設定錯誤: %w", err)
+	}
+	// 共識依商品分別計算 (twse 只有加權、taifex 只有期貨)
+	spotSources, futureSources := CountCoverage(sources)
+	if cfg.QuoteQuorum > spotSources || cfg.QuoteQuorum > futureSources {
+		return nil, fmt.Errorf("QUOTE_QUORUM (%d) 不可大於提供報價的來源數量 (加權: %d, 期貨: %d)",
+			cfg.QuoteQuorum, spotSources, futureSources)
+	}
+	if cfg.QuoteTolerance < 0 {
+		return nil, fmt.Errorf("QUOTE_TOLERANCE 不可為負數")
+	}
+	if cfg.MaxQuoteAge < 0 || cfg.StaleAfter < 0 {
+		return nil, fmt.Errorf("MAX_QUOTE_AGE 與 STALE_AFTER 不可為負數")
+	}
+	if cfg.PriceMin < 0 || cfg.PriceMaxDeviation < 0 || (cfg.PriceMax > 0 && cfg.PriceMax <= cfg.PriceMin) {
+		return nil, fmt.Errorf("報價合理範圍設定錯誤 (PRICE_MIN: %.0f, PRICE_MAX: %.0f, PRICE_MAX_DEVIATION: %.2f)",
+			cfg.PriceMin, cfg.PriceMax, cfg.PriceMaxDeviation)
+	}
+	if cfg.HTTPTimeout <= 0 || cfg.HTTPRetries < 0 {
+		return nil, fmt.Errorf("HTTP_TIMEOUT 必須大於 0 且 HTTP_RETRIES 不可為負數")
+	}
+	if cfg.ScrapeDeadline <= 0 || cfg.MaxFetchSkew < 0 {
+		return nil, fmt.Errorf("SCRAPE_DEADLINE 必須大於 0 且 MAX_FETCH_SKEW 不可為負數")
+	}
+	if cfg.TaskTimeout < 0 || (cfg.TaskTimeout > 0 && cfg.TaskTimeout <= stateSaveTimeout) {
+		return nil, fmt.Errorf("TASK_TIMEOUT 必須大於 %s (0 代表不限制)", stateSaveTimeout)
+	}
+	if _, ok := stateStores[cfg.StateBackend]; !ok {
+		return nil, fmt.Errorf("STATE_BACKEND 必須是 firestore、file 或 memory: %q", cfg.StateBackend)
+	}
+	if cfg.StateBackend == "file" && cfg.StateFile == "" {
+		return nil, fmt.Errorf("STATE_BACKEND=file 時必須設定 STATE_FILE")
+	}
+	if backend := cfg.HistoryStoreBackend(); backend != "none" {
+		if _, ok := historyStores[backend]; !ok {
+			return nil, fmt.Errorf("HISTORY_BACKEND 必須是 firestore、file、memory 或 none: %q", backend)
+		}
+		if backend == "file" && cfg.HistoryDir == "" {
+			return nil, fmt.Errorf("HISTORY_BACKEND=file 時必須設定 HISTORY_DIR")
+		}
+	}
+	if _, err := NewIndexSource(cfg, nil); err != nil {
+		return nil, fmt.Errorf("US_INDEX_SOURCE 設定錯誤: %w", err)
+	}
+	if cfg.PollInterval <= 0 {
+		return nil, fmt.Errorf("POLL_INTERVAL 必須大於 0")
+	}
 	if cfg.Threshold == 0 {
 		log.Println("⚠️ 警告: THRESHOLD 設定為 0，將會頻繁觸發通知")
 	}
@@ -98,6 +208,36 @@ func LoadConfig() (*Config, error) {
 	return cfg, nil
 }
 
+// loadEnvConfig 從環境變數載入設定 (不檢查必填欄位，供不需要通知的子指令使用)
+func loadEnvConfig() (*Config, error) {
+	cfg := &Config{}
+
+	// 使用您的 osenv 庫載入設定
+	if err := osenv.LoadTo(cfg); err != nil {
+		return nil, fmt.Errorf("載入環境變數失敗: %w", err)
+	}
+
+	// osenv 會以逗號切割，含逗號的設定直接讀取環境變數
+	cfg.UserAgent = os.Getenv("HTTP_USER_AGENT")
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = DefaultUserAgent
+	}
+	cfg.AcceptLanguage = os.Getenv("HTTP_ACCEPT_LANGUAGE")
+	if cfg.AcceptLanguage == "" {
+		cfg.AcceptLanguage = DefaultAcceptLanguage
+	}
+
+	var err error
+	if cfg.Selectors, err = LoadSelectors(); err != nil {
+		return nil, fmt.Errorf("爬蟲設定錯誤: %w", err)
+	}
+	if _, err := NewFetcher(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
 var loc *time.Location
 
 func init() {
@@ -110,6 +250,30 @@ func init() {
 
 func main() {
 
+	// 子指令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "probe":
+			if err := runProbe(os.Args[2:]); err != nil {
+				log.Fatalf("❌ probe 失敗: %v", err)
+			}
+			return
+		case "replay":
+			if err := runReplay(os.Args[2:]); err != nil {
+				log.Fatalf("❌ replay 失敗: %v", err)
+			}
+			return
+		case "sweep":
+			if err := runSweep(os.Args[2:]); err != nil {
+				log.Fatalf("❌ sweep 失敗: %v", err)
+			}
+			return
+		}
+	}
+
+	daemon := flag.Bool("daemon", false, "常駐模式: 在程式內依盤別時間排程檢查 (預設為單次執行，由 Cloud Scheduler 觸發)")
+	flag.Parse()
+
 	// 設定提取與驗證 (Fail-Fast)
 	cfg, err := LoadConfig()
 	if err != nil {
@@ -117,130 +281,70 @@ func main() {
 		log.Fatalf("❌ 程式初始化失敗: %v", err)
 	}
 
-	fmt.Println("啟動排程檢查...")
+	// 根 context: 收到 SIGTERM 時取消
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// 休市判斷
-	if IsTodayInDateList(cfg.SpecialDates, loc) {
-		fmt.Println("☕ 今天是預設休市日，程式結束。")
-		return // 直接中斷
+	f, err := NewFetcher(cfg)
+	if err != nil {
+		log.Fatalf("❌ 程式初始化失敗: %v", err)
 	}
-
-	// --- 判斷盤別 ---
-	session, isTrading := GetSessionType(loc)
-	fmt.Printf("目前時段: %s, 是否交易中: %v\n", session, isTrading)
-
-	if !isTrading {
-		fmt.Println("目前非監控時段，結束程式。")
-		return
+	store, err := NewStateStore(cfg)
+	if err != nil {
+		log.Fatalf("❌ 程式初始化失敗: %v", err)
+	}
+	defer store.Close()
+	if cfg.StateBackend == "memory" && !*daemon {
+		log.Println("⚠️ 警告: STATE_BACKEND=memory 在單次執行時不會保留狀態")
 	}
 
-	// 從 Firestore 讀取上次被通知時的價差
-	d, err := GetLastNotifiedData(cfg.GCPProject)
+	history, err := NewHistoryStore(cfg, store)
 	if err != nil {
-		// 進入此處代表發生了「初始化客戶端失敗」或「讀取文件失敗（非不存在）」
-		// 這是無法運行業務邏輯的致命錯誤 (配置、權限、網路連線等)
-		log.Fatalf("❌ Firestore 狀態讀取發生致命錯誤，請檢查配置與權限: %v", err)
-	}
-
-	if DebugEnv == "1" || strings.ToUpper(DebugEnv) == "TRUE" {
-		fmt.Printf("%+v\n", d.Map()) // DEBUG
-	}
-
-	// --- 執行爬蟲與錯誤狀態管理 ---
-	spotVal, futureVal, scrapeErr := ScrapeData()
-	maxRetries := 3
-	if scrapeErr != nil && spotVal == 0 && (IsTaipexPreOpen(loc) || session == SessionNight) {
-		if futureVal == 0 { // 有機會爬到0
-			for i := 1; i <= maxRetries; i++ {
-				fmt.Printf("⚠️ 盤前/夜盤期貨數值異常 (0), 等待 10秒後重試 (%d/%d)...\n", i, maxRetries)
-				time.Sleep(time.Second * 10) // 等一下再重試
-				_, futureVal, scrapeErr = ScrapeData()
-				if futureVal > 0 {
-					fmt.Printf("✅ 重試成功！取得期貨數值: %.2f\n", futureVal)
-					break // 成功抓到，跳出迴圈
-				}
-			}
-		}
-		// 重試結束後的最終判斷
-		if futureVal > 0 {
-			// 情況 A: 成功取得期貨 (或是原本就有，或是重試後拿到)
-			// 此時我們使用 "上次的加權指數" 來填補 spotVal (因為盤前/夜盤 spot 本來就是 0)
-			spotVal = d.LastTWIIValue
-
-			// 重要：既然我們已經用 fallback 數據修復了，就應該清除錯誤
-			scrapeErr = nil
-		} else {
-			// 情況 B: 重試後期貨依然是 0
-			// 我們不 return，而是確保 scrapeErr 有值，讓後面的 CheckErrorState 處理
-			if scrapeErr == nil {
-				scrapeErr = fmt.Errorf("盤前/夜盤無法取得期貨報價 (數值為 0)")
-			}
-			// 程式繼續往下執行... -> 進到 CheckErrorState -> 記錄錯誤 -> 發送 System Alert -> Save Error -> Exit
-		}
+		log.Fatalf("❌ 程式初始化失敗: %v", err)
+	}
+	if history != nil {
+		defer history.Close()
 	}
 
-	// 🎯 核心：使用 CheckErrorState 處理狀態變化 (正常<->失敗)
-	shouldAlertError, errorMsg := d.CheckErrorState(scrapeErr)
-
-	if shouldAlertError {
-		fmt.Println("狀態改變，發送系統通知...")
-		SendAlert(cfg.TelegramToken, cfg.TelegramChatIDs, errorMsg)
+	indices, err := NewIndexSource(cfg, f)
+	if err != nil {
+		log.Fatalf("❌ 程式初始化失敗: %v", err)
 	}
 
-	// 發生錯誤後的處理：儲存錯誤狀態並退出
-	if scrapeErr != nil {
-		log.Printf("執行失敗: %v (Count: %d)", scrapeErr, d.ErrorCount)
-		// ⚠️ 重要：即使失敗也要儲存，這樣下次才知道 ErrorCount > 0
-		if err := SaveCurrentData(cfg.GCPProject, d); err != nil {
-			log.Printf("❌ 無法儲存錯誤狀態: %v", err)
-		}
-		return // 結束程式
+	notifier, err := NewTelegramNotifier(cfg.TelegramToken, cfg.TelegramChatIDs)
+	if err != nil {
+		log.Fatalf("❌ 程式初始化失敗: %v", err)
 	}
 
-	// --- 以下為成功抓取後的正常業務邏輯 ---
-	// 此時 d.ErrorCount 已經被 CheckErrorState 重置為 0
+	// 只有 Firestore 提供爬蟲設定覆寫
+	override, _ := store.(SelectorsOverrider)
 
-	fmt.Printf("📊 加權指數: %.2f | 台指期: %.2f\n", spotVal, futureVal)
+	r := NewRunner(cfg,
+		&ConfigSources{Config: cfg, Fetcher: f, Override: override},
+		store,
+		notifier,
+	)
+	r.History = history
+	r.Indices = indices
 
-	msg, err := NewMessage(session)
-	if err != nil {
-		log.Fatalf("❌ 無法判斷開盤階段%s", session)
+	if *daemon {
+		RunDaemon(ctx, r, cfg)
+		return
 	}
 
-	alertMsg, shouldNotify := msg.Build(d, spotVal, futureVal, cfg.Threshold, cfg.ThresholdChanged)
+	fmt.Println("啟動排程檢查...")
 
-	// 判斷是否為關鍵時間
-	specificAlterMsg, isSpecificTime := CheckSpecificTimeAlert(loc)
-	if isSpecificTime {
-		shouldNotify = true
-		// 如果沒有符合觸發條件, 但是特定時間點依然發送, 要補上訊息
-		if alertMsg == "" {
-			alertMsg = msg.Info(specificAlterMsg, spotVal, futureVal)
-		}
+	runCtx, cancel := runContext(ctx, cfg)
+	defer cancel()
+	if err := r.RunOnce(runCtx); err != nil {
+		log.Fatalf("❌ %v", err)
 	}
+}
 
-	shouldSave := d.UpdateDailyHighLow(spotVal, futureVal, loc)
-
-	// --- 發送 ---
-	if shouldNotify {
-		fmt.Println("觸發條件，發送 Telegram 通知...")
-		SendAlert(cfg.TelegramToken, cfg.TelegramChatIDs, alertMsg)
-		if err := SaveCurrentData(cfg.GCPProject, d); err != nil {
-			log.Printf("❌ 儲存當前價差失敗: %v\n", err)
-		} else {
-			fmt.Println("✅ 已儲存當前數據作為下次比較的基準。")
-		}
-	} else if shouldSave {
-		fmt.Println("✅ 欄位資料異動，儲存新狀態...")
-		if err := SaveCurrentData(cfg.GCPProject, d); err != nil {
-			log.Printf("❌ 儲存恢復狀態失敗: %v", err)
-		}
-	} else if shouldAlertError { // (這代表剛剛發生了 Recovery)
-		// 如果沒有觸發市場警報，但發生了系統狀態改變 (例如：Fail -> Normal Recovery)
-		// 必須儲存 d，以更新 ErrorCount=0 的狀態。
-		fmt.Println("✅ 系統恢復，儲存新狀態...")
-		if err := SaveCurrentData(cfg.GCPProject, d); err != nil {
-			log.Printf("❌ 儲存恢復狀態失敗: %v", err)
-		}
+// runContext 單次檢查的 context: 任務期限將至時取消，並預留時間儲存狀態
+func runContext(ctx context.Context, cfg *Config) (context.Context, context.CancelFunc) {
+	if cfg.TaskTimeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, cfg.TaskTimeout-stateSaveTimeout)
 }
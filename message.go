@@ -2,15 +2,16 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"time"
 )
 
 // 輔助函式：檢查特定時間點是否觸發提醒 (誤差在 1 分鐘內)
-func CheckSpecificTimeAlert(loc *time.Location) (string, bool) {
+func CheckSpecificTimeAlert(clock Clock, loc *time.Location) (string, bool) {
 
-	currentTime := GetCurrentTime(loc)
+	currentTime := GetCurrentTime(clock, loc)
 
 	// 儲存提醒訊息
 	var alertMsg string
@@ -18,7 +19,7 @@ func CheckSpecificTimeAlert(loc *time.Location) (string, bool) {
 	// 判斷邏輯: 檢查當前時間是否在目標時間的 [目標時間-1分鐘, 目標時間+1分鐘] 區間內
 
 	// 為了確保精確性，這裡加入夏令/非夏令時間判斷
-	isEST, err := IsUSMarketInWinterTime()
+	isEST, err := IsUSMarketInWinterTime(clock)
 	if err != nil {
 		log.Printf("❌ 無法判斷美東冬令時間: %v\n", err)
 	}
@@ -62,6 +63,7 @@ type SessionMessage interface {
 
 type SessionMorningMessage struct {
 	prefix string
+	out    io.Writer // 判斷過程訊息的輸出
 }
 
 func (s *SessionMorningMessage) info(msg string, spotVal, futureVal float64) string {
@@ -78,16 +80,16 @@ func (s *SessionMorningMessage) build(d *Data, spotVal, futureVal, threshold, th
 	changed := diff - d.LastDiffValue
 	// --- 早盤邏輯 ---
 
-	// 1. **【新高/新低優先判斷】** 期貨突破當早高低點
-	if spotVal > d.SpotHigh {
+	// 1. **【新高/新低優先判斷】** 加權突破當日高低點 (0 代表當日尚無紀錄)
+	if d.SpotHigh > 0 && spotVal > d.SpotHigh {
 		shouldNotify = true
-		alertMsg = fmt.Sprintf("%s (趨勢: %s)\n加權當日新高(前高: %.2f)\n台指期權差距: %.2f 點\n加權: %.2f\n期貨: %.2f",
-			s.prefix, "📈", d.SpotHigh, math.Abs(diff), spotVal, futureVal)
+		alertMsg = fmt.Sprintf("%s (趨勢: %s)\n加權當日新高(前高: %.2f)\n台指期權差距: %.2f 點\n加權: %.2f\n期貨: %.2f%s",
+			s.prefix, "📈", d.SpotHigh, math.Abs(diff), spotVal, futureVal, d.PrevSessionInfo())
 
-	} else if spotVal < d.SpotLow {
+	} else if d.SpotLow > 0 && spotVal < d.SpotLow {
 		shouldNotify = true
-		alertMsg = fmt.Sprintf("%s (趨勢: %s)\n加權當日新低(前低: %.2f)\n台指期權差距: %.2f 點\n加權: %.2f\n期貨: %.2f",
-			s.prefix, "📉", d.SpotLow, math.Abs(diff), spotVal, futureVal)
+		alertMsg = fmt.Sprintf("%s (趨勢: %s)\n加權當日新低(前低: %.2f)\n台指期權差距: %.2f 點\n加權: %.2f\n期貨: %.2f%s",
+			s.prefix, "📉", d.SpotLow, math.Abs(diff), spotVal, futureVal, d.PrevSessionInfo())
 
 	} else if (spotVal - d.LastTWIIValue) > thresholdChanged {
 		shouldNotify = true
@@ -107,7 +109,7 @@ func (s *SessionMorningMessage) build(d *Data, spotVal, futureVal, threshold, th
 
 		if math.Abs(changed) < thresholdChanged {
 			shouldNotify = false // 跟上次確認差異過小
-			fmt.Printf("✅ 已超過閾值 (%.2f)，但與上次通知值 (%.2f) 變動幅度不超過 %.2f，抑制通知。\n",
+			fmt.Fprintf(s.out, "✅ 已超過閾值 (%.2f)，但與上次通知值 (%.2f) 變動幅度不超過 %.2f，抑制通知。\n",
 				math.Abs(diff), math.Abs(d.LastDiffValue), thresholdChanged)
 
 		} else if changed > 0 {
@@ -126,7 +128,7 @@ func (s *SessionMorningMessage) build(d *Data, spotVal, futureVal, threshold, th
 
 		if math.Abs(changed) < thresholdChanged {
 			shouldNotify = false // 跟上次確認差異過小
-			fmt.Printf("✅ 已超過閾值 (%.2f)，但與上次通知值 (%.2f) 變動幅度不超過 %.2f，抑制通知。\n",
+			fmt.Fprintf(s.out, "✅ 已超過閾值 (%.2f)，但與上次通知值 (%.2f) 變動幅度不超過 %.2f，抑制通知。\n",
 				math.Abs(diff), math.Abs(d.LastDiffValue), thresholdChanged)
 
 		} else if changed < 0 {
@@ -139,7 +141,7 @@ func (s *SessionMorningMessage) build(d *Data, spotVal, futureVal, threshold, th
 
 	} else {
 		// 未達通知閾值, 早盤不單獨判斷增減幅度超過閾值
-		fmt.Printf("%s 台指期權差距: %.2f(閾值: %.2f), 未達通知閾值\n",
+		fmt.Fprintf(s.out, "%s 台指期權差距: %.2f(閾值: %.2f), 未達通知閾值\n",
 			s.prefix, math.Abs(diff), threshold)
 	}
 
@@ -148,6 +150,7 @@ func (s *SessionMorningMessage) build(d *Data, spotVal, futureVal, threshold, th
 
 type SessionNightMessage struct {
 	prefix string
+	out    io.Writer // 判斷過程訊息的輸出
 }
 
 func (s *SessionNightMessage) info(msg string, spotVal, futureVal float64) string {
@@ -164,16 +167,16 @@ func (s *SessionNightMessage) build(d *Data, spotVal, futureVal, threshold, thre
 	changed := diff - d.LastDiffValue
 	// --- 夜盤邏輯 ---
 
-	// **【新高/新低優先判斷】** 期貨突破當早高低點
-	if futureVal > d.FutureHigh {
+	// **【新高/新低優先判斷】** 期貨突破夜盤高低點 (0 代表本盤尚無紀錄)，附上早盤區間作為參考
+	if d.NightFutureHigh > 0 && futureVal > d.NightFutureHigh {
 		shouldNotify = true
-		alertMsg = fmt.Sprintf("%s (趨勢: %s)\n期貨當日新高(前高: %.2f)\n期貨與早盤收盤差距: %.2f 點\n早盤收盤加權: %.2f\n夜盤期貨: %.2f",
-			s.prefix, "📈", d.FutureHigh, math.Abs(diff), d.LastTWIIValue, futureVal)
+		alertMsg = fmt.Sprintf("%s (趨勢: %s)\n夜盤新高(前高: %.2f, 夜盤區間: %.2f ~ %.2f)\n期貨與早盤收盤差距: %.2f 點\n早盤收盤加權: %.2f\n夜盤期貨: %.2f%s",
+			s.prefix, "📈", d.NightFutureHigh, d.NightFutureLow, d.NightFutureHigh, math.Abs(diff), d.LastTWIIValue, futureVal, d.PrevSessionInfo())
 
-	} else if futureVal < d.FutureLow {
+	} else if d.NightFutureLow > 0 && futureVal < d.NightFutureLow {
 		shouldNotify = true
-		alertMsg = fmt.Sprintf("%s (趨勢: %s)\n期貨當日新低(前低: %.2f)\n期貨與早盤收盤差距: %.2f 點\n早盤收盤加權: %.2f\n夜盤期貨: %.2f",
-			s.prefix, "📉", d.FutureLow, math.Abs(diff), d.LastTWIIValue, futureVal)
+		alertMsg = fmt.Sprintf("%s (趨勢: %s)\n夜盤新低(前低: %.2f, 夜盤區間: %.2f ~ %.2f)\n期貨與早盤收盤差距: %.2f 點\n早盤收盤加權: %.2f\n夜盤期貨: %.2f%s",
+			s.prefix, "📉", d.NightFutureLow, d.NightFutureLow, d.NightFutureHigh, math.Abs(diff), d.LastTWIIValue, futureVal, d.PrevSessionInfo())
 
 		// ** 價差變動超過閾值
 	} else if diff > threshold {
@@ -185,7 +188,7 @@ func (s *SessionNightMessage) build(d *Data, spotVal, futureVal, threshold, thre
 		if math.Abs(changed) < thresholdChanged {
 			shouldNotify = false // 跟上次確認差異過小
 			alertMsg = ""
-			fmt.Printf("✅ 已超過閾值 (%.2f)，但與上次通知值 (%.2f) 變動幅度不超過 %.2f，抑制通知。\n",
+			fmt.Fprintf(s.out, "✅ 已超過閾值 (%.2f)，但與上次通知值 (%.2f) 變動幅度不超過 %.2f，抑制通知。\n",
 				math.Abs(diff), math.Abs(d.LastDiffValue), thresholdChanged)
 
 		} else if changed < 0 {
@@ -205,7 +208,7 @@ func (s *SessionNightMessage) build(d *Data, spotVal, futureVal, threshold, thre
 		if math.Abs(changed) < thresholdChanged {
 			shouldNotify = false // 跟上次確認差異過小
 			alertMsg = ""
-			fmt.Printf("✅ 已超過閾值 (%.2f)，但與上次通知值 (%.2f) 變動幅度不超過 %.2f，抑制通知。\n",
+			fmt.Fprintf(s.out, "✅ 已超過閾值 (%.2f)，但與上次通知值 (%.2f) 變動幅度不超過 %.2f，抑制通知。\n",
 				math.Abs(diff), math.Abs(d.LastDiffValue), thresholdChanged)
 
 		} else if changed > 0 {
@@ -251,21 +254,21 @@ func (s *SessionNightMessage) build(d *Data, spotVal, futureVal, threshold, thre
 
 	} else {
 		// 未達通知閾值
-		fmt.Printf("%s 期貨與早盤收盤差距: %.2f(閾值: %.2f), 期貨漲跌幅度: %.2f(閾值: %.2f), 均未達通知閾值\n",
+		fmt.Fprintf(s.out, "%s 期貨與早盤收盤差距: %.2f(閾值: %.2f), 期貨漲跌幅度: %.2f(閾值: %.2f), 均未達通知閾值\n",
 			s.prefix, math.Abs(diff), threshold, math.Abs(changed), thresholdChanged)
 	}
 
 	return alertMsg, shouldNotify
 }
 
-func newSessionMessage(s string) (SessionMessage, error) {
+func newSessionMessage(s string, out io.Writer) (SessionMessage, error) {
 	var o SessionMessage = nil
 	var err error
 	switch s {
 	case SessionMorning:
-		o = &SessionMorningMessage{"☀️ [早盤警示]"}
+		o = &SessionMorningMessage{prefix: "☀️ [早盤警示]", out: out}
 	case SessionNight:
-		o = &SessionNightMessage{"🌙 [夜盤警示]"}
+		o = &SessionNightMessage{prefix: "🌙 [夜盤警示]", out: out}
 	default:
 		err = fmt.Errorf("未知市場%s", s)
 	}
@@ -278,8 +281,9 @@ type Message struct {
 	d *Data
 }
 
-func NewMessage(s string) (*Message, error) {
-	sm, err := newSessionMessage(s)
+// NewMessage 建立盤別的通知訊息，out 為判斷過程訊息 (抑制通知、未達閾值等) 的輸出
+func NewMessage(s string, out io.Writer) (*Message, error) {
+	sm, err := newSessionMessage(s, out)
 	if err != nil {
 		return nil, err
 	}
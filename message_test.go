@@ -1,6 +1,7 @@
 package main
 
 import (
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -20,6 +21,9 @@ func TestMessage_Build(t *testing.T) {
 		SpotLow:        19900.0,
 		FutureHigh:     20100.0,
 		FutureLow:      19900.0,
+
+		NightFutureHigh: 20100.0,
+		NightFutureLow:  19900.0,
 	}
 
 	tests := []struct {
@@ -82,13 +86,33 @@ func TestMessage_Build(t *testing.T) {
 			session:          SessionNight,
 			threshold:        baseThreshold,
 			thresholdChanged: baseThresholdChanged,
-			d:                baseData, // FutureLow = 19900
+			d:                baseData, // NightFutureLow = 19900
 			// 情境：期貨崩跌到 19000 (破新低)，現貨 20000
 			// 價差 = 1000 (絕對值 1000 > 50 閾值)
 			spotVal:          20000.0,
 			futureVal:        19000.0,
 			wantNotify:       true,
-			wantMsgSubstring: "期貨當日新低",
+			wantMsgSubstring: "夜盤新低",
+		},
+
+		{
+			name:             "夜盤新高_附上早盤收盤與區間",
+			session:          SessionNight,
+			threshold:        baseThreshold,
+			thresholdChanged: baseThresholdChanged,
+			d: &Data{
+				LastTWIIValue:   20000,
+				NightFutureHigh: 20050,
+				NightFutureLow:  19980,
+				PrevSession:     SessionMorning,
+				PrevClose:       20010,
+				PrevHigh:        20120,
+				PrevLow:         19950,
+			},
+			spotVal:          20000.0,
+			futureVal:        20060.0,
+			wantNotify:       true,
+			wantMsgSubstring: "夜盤新高(前高: 20050.00, 夜盤區間: 19980.00 ~ 20050.00)\n期貨與早盤收盤差距: 60.00 點\n早盤收盤加權: 20000.00\n夜盤期貨: 20060.00\n早盤期貨收盤: 20010.00 (區間: 19950.00 ~ 20120.00)",
 		},
 
 		// --- 漲跌方向測試 ---
@@ -254,7 +278,7 @@ func TestMessage_Build(t *testing.T) {
 
 		t.Run(tt.name, func(t *testing.T) {
 			// 初始化 Message
-			msg, err := NewMessage(tt.session)
+			msg, err := NewMessage(tt.session, os.Stdout)
 			if err != nil {
 				t.Fatalf("NewMessage failed: %v", err)
 			}
@@ -276,3 +300,67 @@ func TestMessage_Build(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckSpecificTimeAlert(t *testing.T) {
+	winter := func(hour, min int) time.Time { return time.Date(2026, 1, 15, hour, min, 0, 0, loc) }
+	summer := func(hour, min int) time.Time { return time.Date(2026, 7, 15, hour, min, 0, 0, loc) }
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		wantMsg string // 空字串代表不觸發
+	}{
+		// 台股時間不受美國夏令影響
+		{"期貨開盤前_冬令", winter(8, 43), ""},
+		{"期貨開盤_視窗起點", winter(8, 44), "08:45"},
+		{"期貨開盤_視窗終點", summer(8, 46), "08:45"},
+		{"期貨開盤後", winter(8, 47), ""},
+		{"現貨開盤_視窗起點", summer(8, 59), "09:00"},
+		{"現貨開盤_視窗終點", winter(9, 1), "09:00"},
+		{"現貨開盤後", winter(9, 2), ""},
+		{"夜盤開盤_視窗起點", winter(14, 59), "15:00"},
+		{"夜盤開盤_視窗終點", summer(15, 1), "15:00"},
+		{"夜盤開盤後", summer(15, 2), ""},
+
+		// 美股盤前
+		{"美股盤前_冬令", winter(17, 0), "17:00 - 冬令時間"},
+		{"美股盤前_冬令_視窗起點", winter(16, 59), "17:00 - 冬令時間"},
+		{"美股盤前_冬令_視窗終點", winter(17, 1), "17:00 - 冬令時間"},
+		{"冬令時不觸發夏令盤前", winter(16, 0), ""},
+		{"美股盤前_夏令", summer(16, 0), "16:00 - 夏令時間"},
+		{"美股盤前_夏令_視窗起點", summer(15, 59), "16:00 - 夏令時間"},
+		{"美股盤前_夏令_視窗終點", summer(16, 1), "16:00 - 夏令時間"},
+		{"夏令時不觸發冬令盤前", summer(17, 0), ""},
+
+		// 美股開盤
+		{"美股開盤_冬令", winter(22, 30), "22:30 - 冬令時間"},
+		{"美股開盤_冬令_視窗起點", winter(22, 29), "22:30 - 冬令時間"},
+		{"美股開盤_冬令_視窗終點", winter(22, 31), "22:30 - 冬令時間"},
+		{"美股開盤_冬令_視窗外", winter(22, 32), ""},
+		{"冬令時不觸發夏令開盤", winter(21, 30), ""},
+		{"美股開盤_夏令", summer(21, 30), "21:30 - 夏令時間"},
+		{"美股開盤_夏令_視窗起點", summer(21, 29), "21:30 - 夏令時間"},
+		{"美股開盤_夏令_視窗終點", summer(21, 31), "21:30 - 夏令時間"},
+		{"夏令時不觸發冬令開盤", summer(22, 30), ""},
+
+		// 夏令時間切換當天 (2026-03-08 台北 15:00 起為夏令)
+		{"切換夏令當天_美股開盤", time.Date(2026, 3, 8, 21, 30, 0, 0, loc), "21:30 - 夏令時間"},
+		{"切換冬令當天_美股開盤", time.Date(2026, 11, 1, 22, 30, 0, 0, loc), "22:30 - 冬令時間"},
+
+		// 一般盤中
+		{"早盤盤中", winter(10, 30), ""},
+		{"夜盤盤中", summer(2, 0), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, ok := CheckSpecificTimeAlert(NewFakeClock(tt.now), loc)
+			if ok != (tt.wantMsg != "") {
+				t.Fatalf("CheckSpecificTimeAlert(%s) = (%q, %v), want trigger %v", tt.now.Format("01-02 15:04"), msg, ok, tt.wantMsg != "")
+			}
+			if !strings.Contains(msg, tt.wantMsg) {
+				t.Errorf("CheckSpecificTimeAlert(%s) = %q, want substring %q", tt.now.Format("01-02 15:04"), msg, tt.wantMsg)
+			}
+		})
+	}
+}
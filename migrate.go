@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// 狀態文件目前的格式版本
+// 變更欄位名稱或型別時遞增，並在 dataMigrations 加入對應的升級函式
+const currentSchemaVersion = 1
+
+// dataMigration 將 From 版本的狀態文件升級為 From+1 版本
+type dataMigration struct {
+	From  int
+	Desc  string
+	Apply func(m map[string]interface{}) error
+}
+
+// dataMigrations 依 From 排序的升級函式
+var dataMigrations = []dataMigration{
+	{From: 0, Desc: "LastUpdateTime 由 Unix 秒數改為時間", Apply: migrateV0},
+}
+
+// MigrateData 將舊版狀態文件 (Firestore 原始欄位) 依序升級為目前版本
+// 回傳: 是否有升級 (需要寫回)
+func MigrateData(m map[string]interface{}) (bool, error) {
+	version, err := schemaVersion(m)
+	if err != nil {
+		return false, err
+	}
+	if version > currentSchemaVersion {
+		return false, fmt.Errorf("狀態文件版本 %d 比程式支援的版本 %d 新，請更新程式", version, currentSchemaVersion)
+	}
+
+	from := version
+	for _, mg := range dataMigrations {
+		if mg.From != version {
+			continue
+		}
+		if err := mg.Apply(m); err != nil {
+			return false, fmt.Errorf("狀態文件由版本 %d 升級失敗 (%s): %w", version, mg.Desc, err)
+		}
+		version++
+		m["SchemaVersion"] = int64(version)
+	}
+	if version != currentSchemaVersion {
+		return false, fmt.Errorf("缺少狀態文件版本 %d 的升級函式", version)
+	}
+	return version != from, nil
+}
+
+// schemaVersion 讀取文件的格式版本 (沒有 SchemaVersion 欄位的舊文件為 0)
+func schemaVersion(m map[string]interface{}) (int, error) {
+	val, ok := m["SchemaVersion"]
+	if !ok || val == nil {
+		return 0, nil
+	}
+	switch v := val.(type) {
+	case int64:
+		return int(v), nil
+	case int:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("無法解析狀態文件版本: %v (%T)", val, val)
+	}
+}
+
+// migrateV0 版本 0: 沒有 SchemaVersion 的文件
+// 最早的文件以 Unix 秒數 (int64) 儲存 LastUpdateTime，之後才改為時間
+// 其餘欄位都是後來陸續新增，缺少的欄位解析時為零值，不需要處理
+// (整數的價格欄位由 DataTo 轉為 float64)
+func migrateV0(m map[string]interface{}) error {
+	switch v := m["LastUpdateTime"].(type) {
+	case int64:
+		m["LastUpdateTime"] = time.Unix(v, 0)
+	case time.Time, nil:
+	default:
+		return fmt.Errorf("LastUpdateTime 型別不正確: %T", v)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMigrateData(t *testing.T) {
+	updated := time.Date(2025, 11, 3, 9, 30, 0, 0, loc)
+
+	tests := []struct {
+		name         string
+		doc          map[string]interface{}
+		wantMigrated bool
+		wantErr      bool
+		wantTime     time.Time // 升級後的 LastUpdateTime (零值代表欄位不存在)
+	}{
+		{
+			name: "版本0_最早格式_時間為Unix秒數",
+			doc: map[string]interface{}{
+				"LastTWIIValue":  17000.5,
+				"LastDiffValue":  -20.0,
+				"LastUpdateTime": updated.Unix(),
+				"ErrorCount":     int64(0),
+				"LastError":      "",
+			},
+			wantMigrated: true,
+			wantTime:     updated,
+		},
+		{
+			name: "版本0_加入高低點_價格為整數",
+			doc: map[string]interface{}{
+				"LastTWIIValue":  int64(27000),
+				"LastDiffValue":  -35.25,
+				"LastUpdateTime": updated,
+				"SpotHigh":       int64(27100),
+				"SpotLow":        26900.0,
+				"FutureHigh":     27120.0,
+				"FutureLow":      26880.0,
+				"ErrorCount":     int64(2),
+				"LastError":      "連線逾時",
+			},
+			wantMigrated: true,
+			wantTime:     updated,
+		},
+		{
+			name: "版本0_加入來源與停滯欄位",
+			doc: map[string]interface{}{
+				"LastTWIIValue":     27000.0,
+				"LastUpdateTime":    updated,
+				"SpotSource":        "twse",
+				"FutureSource":      "taifex",
+				"FutureContract":    "TXFK6",
+				"SourceNote":        "",
+				"LastFutureValue":   26950.0,
+				"SpotQuoteTime":     updated,
+				"FutureQuoteTime":   updated,
+				"SpotRepeatCount":   int64(1),
+				"FutureRepeatCount": int64(0),
+			},
+			wantMigrated: true,
+			wantTime:     updated,
+		},
+		{
+			name:         "版本0_空文件",
+			doc:          map[string]interface{}{},
+			wantMigrated: true,
+		},
+		{
+			name: "目前版本_不需升級",
+			doc: map[string]interface{}{
+				"SchemaVersion":  int64(currentSchemaVersion),
+				"LastTWIIValue":  27000.0,
+				"LastUpdateTime": updated,
+			},
+			wantTime: updated,
+		},
+		{
+			name: "版本較新_回傳錯誤",
+			doc: map[string]interface{}{
+				"SchemaVersion": int64(currentSchemaVersion + 1),
+			},
+			wantErr: true,
+		},
+		{
+			name: "時間欄位型別錯誤_回傳錯誤",
+			doc: map[string]interface{}{
+				"LastUpdateTime": "2025-11-03",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrated, err := MigrateData(tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MigrateData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if migrated != tt.wantMigrated {
+				t.Errorf("MigrateData() migrated = %v, want %v", migrated, tt.wantMigrated)
+			}
+			if v, _ := schemaVersion(tt.doc); v != currentSchemaVersion {
+				t.Errorf("MigrateData() SchemaVersion = %d, want %d", v, currentSchemaVersion)
+			}
+
+			got, _ := tt.doc["LastUpdateTime"].(time.Time)
+			if !got.Equal(tt.wantTime) {
+				t.Errorf("MigrateData() LastUpdateTime = %v, want %v", tt.doc["LastUpdateTime"], tt.wantTime)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/antchfx/htmlquery"
+	"github.com/joho/godotenv"
+)
+
+// probe 子指令: 檢查 XPath 是否仍然有效，並保存網頁快照供離線比對
+//
+// 用法: watchtwii probe [-out snapshots] [-depth 2]
+
+// ProbeTarget 要檢查的頁面與 XPath
+type ProbeTarget struct {
+	Name      string
+	URL       string
+	XPath     string
+	Fallbacks []string // 候選 XPath
+}
+
+// ProbeMatch 單一 XPath 的檢查結果
+type ProbeMatch struct {
+	XPath   string
+	Matched bool
+	Text    string // 節點文字
+	Context string // 節點周圍的 DOM
+	Err     error  // XPath 語法錯誤
+}
+
+// ProbeReport 單一頁面的檢查報告
+type ProbeReport struct {
+	Target    ProbeTarget
+	Snapshot  string // 快照檔案路徑
+	Primary   ProbeMatch
+	Fallbacks []ProbeMatch
+}
+
+// 周圍 DOM 最多輸出的字元數
+const probeContextLimit = 1500
+
+// Probe 下載頁面、保存快照，並檢查主要與候選 XPath
+// depth 為輸出周圍 DOM 時往上取幾層父節點
+func Probe(ctx context.Context, f *Fetcher, t ProbeTarget, outDir string, depth int, now time.Time) (*ProbeReport, error) {
+	body, err := f.Get(ctx, t.URL)
+	if err != nil {
+		return nil, fmt.Errorf("載入 URL 失敗: %v", err)
+	}
+
+	report := &ProbeReport{Target: t}
+
+	if outDir != "" {
+		if err := os.MkdirAll(outDir, 0o755); err != nil {
+			return nil, fmt.Errorf("建立快照目錄失敗: %w", err)
+		}
+		report.Snapshot = filepath.Join(outDir, fmt.Sprintf("%s-%s.html", t.Name, now.In(loc).Format("20060102-150405")))
+		if err := os.WriteFile(report.Snapshot, body, 0o644); err != nil {
+			return nil, fmt.Errorf("寫入快照失敗: %w", err)
+		}
+	}
+
+	doc, err := htmlquery.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("解析 HTML 失敗: %v", err)
+	}
+
+	evaluate := func(expr string) ProbeMatch {
+		m := ProbeMatch{XPath: expr}
+		node, err := htmlquery.Query(doc, expr)
+		if err != nil {
+			m.Err = err
+			return m
+		}
+		if node == nil {
+			return m
+		}
+
+		m.Matched = true
+		m.Text = strings.TrimSpace(htmlquery.InnerText(node))
+
+		// 往上取父節點，方便找出新的節點位置
+		around := node
+		for i := 0; i < depth && around.Parent != nil; i++ {
+			around = around.Parent
+		}
+		m.Context = htmlquery.OutputHTML(around, true)
+		if len(m.Context) > probeContextLimit {
+			m.Context = m.Context[:probeContextLimit] + "..."
+		}
+		return m
+	}
+
+	report.Primary = evaluate(t.XPath)
+	for _, expr := range t.Fallbacks {
+		report.Fallbacks = append(report.Fallbacks, evaluate(expr))
+	}
+
+	return report, nil
+}
+
+// Print 輸出檢查報告
+func (r *ProbeReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "=== %s ===\nURL: %s\n", r.Target.Name, r.Target.URL)
+	if r.Snapshot != "" {
+		fmt.Fprintf(w, "快照: %s\n", r.Snapshot)
+	}
+
+	printMatch := func(label string, m ProbeMatch, withContext bool) {
+		switch {
+		case m.Err != nil:
+			fmt.Fprintf(w, "⚠️ %s XPath 語法錯誤: %s\n   %v\n", label, m.XPath, m.Err)
+		case m.Matched:
+			fmt.Fprintf(w, "✅ %s %s\n   文字: %q\n", label, m.XPath, m.Text)
+			if withContext {
+				fmt.Fprintf(w, "   周圍 DOM:\n%s\n", m.Context)
+			}
+		default:
+			fmt.Fprintf(w, "❌ %s %s\n", label, m.XPath)
+		}
+	}
+
+	printMatch("[設定]", r.Primary, true)
+	for _, m := range r.Fallbacks {
+		printMatch("[候選]", m, false)
+	}
+	fmt.Fprintln(w)
+}
+
+func runProbe(args []string) error {
+	fs := flag.NewFlagSet("probe", flag.ExitOnError)
+	outDir := fs.String("out", "snapshots", "HTML 快照輸出目錄 (空字串代表不保存)")
+	depth := fs.Int("depth", 2, "輸出周圍 DOM 時往上取幾層父節點")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// probe 不需要 Telegram 等設定，只載入爬蟲設定
+	godotenv.Load()
+	cfg, err := loadEnvConfig()
+	if err != nil {
+		return err
+	}
+	f, err := NewFetcher(cfg)
+	if err != nil {
+		return err
+	}
+	sel := cfg.Selectors
+
+	targets := []ProbeTarget{
+		{Name: "spot", URL: sel.SpotURL, XPath: sel.SpotXPath, Fallbacks: sel.SpotCandidates()},
+		{Name: "future", URL: sel.FutureURL, XPath: sel.FutureXPath, Fallbacks: sel.FutureCandidates()},
+	}
+
+	now := time.Now()
+	failed := 0
+	for _, t := range targets {
+		report, err := Probe(ctx, f, t, *outDir, *depth, now)
+		if err != nil {
+			fmt.Printf("=== %s ===\n❌ %v\n\n", t.Name, err)
+			failed++
+			continue
+		}
+		report.Print(os.Stdout)
+		if !report.Primary.Matched {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d 個頁面的設定 XPath 無法取得節點", failed)
+	}
+	return nil
+}
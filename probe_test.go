@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbe(t *testing.T) {
+	srv := newFixtureServer(t, "testdata/yahoo_spot.html")
+	outDir := t.TempDir()
+
+	target := ProbeTarget{
+		Name:  "spot",
+		URL:   srv.URL,
+		XPath: DefaultSpotXPath,
+		Fallbacks: []string{
+			"//span[contains(@class,'Fz(32px)')]",
+			"//span[@id='missing']",
+			"//span[",
+		},
+	}
+
+	report, err := Probe(context.Background(), newTestFetcher(), target, outDir, 1, time.Date(2025, 10, 16, 9, 30, 0, 0, loc))
+	if err != nil {
+		t.Fatalf("Probe() unexpected error: %v", err)
+	}
+
+	if !report.Primary.Matched || report.Primary.Text != "27,201.64" {
+		t.Errorf("Probe() primary = %+v, want match 27,201.64", report.Primary)
+	}
+	if !strings.Contains(report.Primary.Context, "67.71") {
+		t.Errorf("Probe() context = %v, want sibling nodes", report.Primary.Context)
+	}
+
+	wantMatched := []bool{true, false, false}
+	for i, m := range report.Fallbacks {
+		if m.Matched != wantMatched[i] {
+			t.Errorf("Probe() fallback %s matched = %v, want %v", m.XPath, m.Matched, wantMatched[i])
+		}
+	}
+	if report.Fallbacks[2].Err == nil {
+		t.Errorf("Probe() fallback with invalid syntax want error")
+	}
+
+	snapshot, err := os.ReadFile(report.Snapshot)
+	if err != nil {
+		t.Fatalf("讀取快照失敗: %v", err)
+	}
+	if !strings.Contains(string(snapshot), "27,201.64") {
+		t.Errorf("Probe() snapshot does not contain page content")
+	}
+
+	var out bytes.Buffer
+	report.Print(&out)
+	if !strings.Contains(out.String(), "❌ [候選] //span[@id='missing']") {
+		t.Errorf("Print() = %v, want missing fallback reported", out.String())
+	}
+}
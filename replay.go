@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// replay 子指令: 以歷史報價重播通知規則，評估 THRESHOLD / THRESHOLD_CHANGED 的調整
+//
+// 用法: watchtwii replay [-csv ticks.csv | -from 2026-10-01 -to 2026-10-16] [-threshold 70] [-threshold-changed 35] [-v]
+
+// ReplayAlert 重播時會發送的通知
+type ReplayAlert struct {
+	Time    time.Time
+	Day     string // 交易日
+	Session string
+	Future  float64 // 通知當下的期貨
+	Message string
+}
+
+// ReplayDay 單一交易日的重播統計
+type ReplayDay struct {
+	Day    string
+	Ticks  int // 納入判斷的報價筆數
+	Alerts int
+}
+
+// ReplayResult 重播結果
+type ReplayResult struct {
+	Threshold        float64
+	ThresholdChanged float64
+	Alerts           []ReplayAlert
+	Days             []ReplayDay // 依交易日排序
+}
+
+// Total 通知總數
+func (r *ReplayResult) Total() int {
+	return len(r.Alerts)
+}
+
+// Replay 以模擬時鐘依序重播歷史報價，套用與 Runner 相同的判斷
+// (換日重置、新高新低、價差閾值、關鍵時間提醒、高低點更新)
+// 只重播判斷規則: 不檢查報價停滯，也不發送通知
+// out 為判斷過程訊息的輸出 (不需要時傳入 io.Discard)
+func Replay(cfg *Config, ticks []Tick, out io.Writer) *ReplayResult {
+	rc := *cfg
+	rc.StaleAfter = 0 // 歷史紀錄只有成功抓取的報價 (停滯時不會記錄)，停滯檢查沒有意義
+
+	ticks = append([]Tick{}, ticks...)
+	sort.SliceStable(ticks, func(i, j int) bool { return ticks[i].Time.Before(ticks[j].Time) })
+
+	clock := NewFakeClock(time.Time{})
+	r := &Runner{Config: &rc, Clock: clock, Out: out}
+	state := &Data{}
+
+	result := &ReplayResult{Threshold: cfg.Threshold, ThresholdChanged: cfg.ThresholdChanged}
+	days := map[string]*ReplayDay{}
+	var order []string
+	first := true
+
+	for _, t := range ticks {
+		clock.Set(t.Time)
+		session, isTrading := SessionAt(t.Time, loc)
+		if !isTrading || IsTodayInDateList(clock, cfg.SpecialDates, loc) || t.Future <= 0 {
+			continue
+		}
+		// 與 Runner 相同: 夜盤與盤前的加權為前次收盤
+		spotFallback := session == SessionNight ||
+			(IsTaipexPreOpen(clock, loc) && (t.Spot <= 0 || t.SpotSource == spotFallbackSource))
+		if !spotFallback && t.Spot <= 0 {
+			continue
+		}
+
+		dayKey := t.TradingDay()
+		day, ok := days[dayKey]
+		if !ok {
+			day = &ReplayDay{Day: dayKey}
+			days[dayKey] = day
+			order = append(order, dayKey)
+		}
+		day.Ticks++
+
+		res := ScrapeResult{
+			Spot:   Quote{Value: t.Spot, Time: t.Time, Source: t.SpotSource},
+			Future: Quote{Value: t.Future, Time: t.Time, Source: t.FutureSource, Contract: t.Contract},
+		}
+
+		// Runner 只有在需要儲存時才保留這次的狀態變更
+		d := *state
+		e, err := r.evaluate(&d, session, res, nil, spotFallback)
+		if err != nil {
+			continue
+		}
+		if e.save {
+			d.LastUpdateTime = t.Time
+			state = &d
+		}
+		if first {
+			// 第一筆報價只用來建立基準 (前值為 0，任何判斷都會觸發)
+			first = false
+			continue
+		}
+		if e.alert != "" {
+			result.Alerts = append(result.Alerts, ReplayAlert{Time: t.Time, Day: dayKey, Session: session, Future: t.Future, Message: e.alert})
+			day.Alerts++
+		}
+	}
+
+	for _, key := range order {
+		result.Days = append(result.Days, *days[key])
+	}
+	return result
+}
+
+// Print 輸出重播結果 (verbose 時輸出完整通知內容)
+func (r *ReplayResult) Print(w io.Writer, verbose bool) {
+	fmt.Fprintf(w, "=== 重播結果 (THRESHOLD: %.2f, THRESHOLD_CHANGED: %.2f) ===\n", r.Threshold, r.ThresholdChanged)
+	for _, a := range r.Alerts {
+		msg := a.Message
+		if !verbose {
+			// 只保留標題與觸發原因
+			lines := strings.SplitN(msg, "\n", 3)
+			msg = strings.Join(lines[:min(len(lines), 2)], " | ")
+		}
+		fmt.Fprintf(w, "[%s] %s\n", a.Time.In(loc).Format(time.DateTime), msg)
+		if verbose {
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "交易日\t報價筆數\t通知數")
+	ticks := 0
+	for _, d := range r.Days {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", d.Day, d.Ticks, d.Alerts)
+		ticks += d.Ticks
+	}
+	fmt.Fprintf(tw, "合計\t%d\t%d\n", ticks, r.Total())
+	tw.Flush()
+}
+
+// ReadTicksCSV 讀取 CSV 格式的歷史報價
+// 第一列為欄位名稱: time, future 為必填; spot (夜盤可留空), contract 為選填
+// time 可為 RFC 3339 或台北時間的 "2006-01-02 15:04:05"
+func ReadTicksCSV(r io.Reader) ([]Tick, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("讀取 CSV 欄位名稱失敗: %w", err)
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"time", "future"} {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("CSV 缺少 %s 欄位", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var ticks []Tick
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("讀取 CSV 第 %d 列失敗: %w", line, err)
+		}
+
+		t := Tick{SpotSource: "csv", FutureSource: "csv", Contract: field(record, "contract")}
+		raw := field(record, "time")
+		if t.Time, err = time.Parse(time.RFC3339, raw); err != nil {
+			if t.Time, err = time.ParseInLocation(time.DateTime, raw, loc); err != nil {
+				return nil, fmt.Errorf("CSV 第 %d 列時間格式錯誤: %q", line, raw)
+			}
+		}
+		if t.Future, err = ParseToFloat(field(record, "future")); err != nil {
+			return nil, fmt.Errorf("CSV 第 %d 列期貨格式錯誤: %w", line, err)
+		}
+		if spot := field(record, "spot"); spot != "" {
+			if t.Spot, err = ParseToFloat(spot); err != nil {
+				return nil, fmt.Errorf("CSV 第 %d 列加權格式錯誤: %w", line, err)
+			}
+		}
+		t.Session, _ = SessionAt(t.Time, loc)
+		t.Diff = t.Spot - t.Future
+		ticks = append(ticks, t)
+	}
+	return ticks, nil
+}
+
+// loadReplayTicks 讀取重播用的報價: 指定 CSV 時讀取檔案，否則從歷史紀錄讀取 from ~ to (含) 的交易日
+func loadReplayTicks(ctx context.Context, cfg *Config, csvPath, from, to string) ([]Tick, error) {
+	if csvPath != "" {
+		file, err := os.Open(csvPath)
+		if err != nil {
+			return nil, fmt.Errorf("開啟 CSV 失敗: %w", err)
+		}
+		defer file.Close()
+		return ReadTicksCSV(file)
+	}
+
+	if from == "" {
+		return nil, fmt.Errorf("未指定 -csv 時必須指定 -from")
+	}
+	if to == "" {
+		to = from
+	}
+	fromDay, err := time.ParseInLocation(time.DateOnly, from, loc)
+	if err != nil {
+		return nil, fmt.Errorf("-from 日期格式錯誤: %q", from)
+	}
+	toDay, err := time.ParseInLocation(time.DateOnly, to, loc)
+	if err != nil {
+		return nil, fmt.Errorf("-to 日期格式錯誤: %q", to)
+	}
+
+	history, err := NewHistoryStore(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		return nil, fmt.Errorf("HISTORY_BACKEND=none，沒有歷史紀錄可以重播")
+	}
+	defer history.Close()
+
+	// 交易日從 05:01 開始 (00:00 ~ 05:00 屬於前一天開盤的夜盤)
+	dayStart := 5*time.Hour + time.Minute
+	return history.Query(ctx, fromDay.Add(dayStart), toDay.AddDate(0, 0, 1).Add(dayStart))
+}
+
+func runReplay(args []string) error {
+	// replay 不需要 Telegram 等設定，只載入環境變數中的閾值與休市日
+	godotenv.Load()
+	cfg, err := loadEnvConfig()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	csvPath := fs.String("csv", "", "歷史報價 CSV (欄位: time,spot,future[,contract])，未指定時讀取歷史紀錄")
+	from := fs.String("from", "", "歷史紀錄的起始交易日 (2006-01-02)")
+	to := fs.String("to", "", "歷史紀錄的結束交易日 (含，預設同 -from)")
+	threshold := fs.Float64("threshold", cfg.Threshold, "價差閾值 (預設為 THRESHOLD)")
+	thresholdChanged := fs.Float64("threshold-changed", cfg.ThresholdChanged, "變動幅度閾值 (預設為 THRESHOLD_CHANGED)")
+	verbose := fs.Bool("v", false, "輸出完整通知內容與判斷過程")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg.Threshold, cfg.ThresholdChanged = *threshold, *thresholdChanged
+
+	ticks, err := loadReplayTicks(context.Background(), cfg, *csvPath, *from, *to)
+	if err != nil {
+		return err
+	}
+	if len(ticks) == 0 {
+		return fmt.Errorf("沒有可重播的報價")
+	}
+
+	// 判斷過程只在 -v 時輸出
+	var out io.Writer = io.Discard
+	if *verbose {
+		out = os.Stdout
+	}
+	result := Replay(cfg, ticks, out)
+
+	result.Print(os.Stdout, *verbose)
+	return nil
+}
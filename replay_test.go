@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadReplayFixture(t *testing.T) []Tick {
+	t.Helper()
+	file, err := os.Open("testdata/replay_ticks.csv")
+	if err != nil {
+		t.Fatalf("開啟測試資料失敗: %v", err)
+	}
+	defer file.Close()
+
+	ticks, err := ReadTicksCSV(file)
+	if err != nil {
+		t.Fatalf("ReadTicksCSV() unexpected error: %v", err)
+	}
+	return ticks
+}
+
+func TestReadTicksCSV(t *testing.T) {
+	ticks := loadReplayFixture(t)
+	if len(ticks) != 12 {
+		t.Fatalf("ReadTicksCSV() = %d ticks, want 12", len(ticks))
+	}
+
+	night := ticks[9] // 2026-10-16 01:00 (夜盤，加權留空)
+	if !night.Time.Equal(time.Date(2026, 10, 16, 1, 0, 0, 0, loc)) || night.Session != SessionNight ||
+		night.Spot != 0 || night.Future != 26900 || night.Contract != "TXFK6" || night.TradingDay() != "2026-10-15" {
+		t.Errorf("ReadTicksCSV()[9] = %+v, want night tick of 2026-10-15", night)
+	}
+
+	errTests := []struct {
+		name string
+		body string
+	}{
+		{"缺少期貨欄位", "time,spot\n2026-10-15 09:00:00,27000\n"},
+		{"時間格式錯誤", "time,spot,future\n10/15 09:00,27000,27000\n"},
+		{"期貨格式錯誤", "time,spot,future\n2026-10-15 09:00:00,27000,-\n"},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadTicksCSV(strings.NewReader(tt.body)); err == nil {
+				t.Errorf("ReadTicksCSV() want error")
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	ticks := loadReplayFixture(t)
+
+	tests := []struct {
+		name             string
+		threshold        float64
+		thresholdChanged float64
+		holidays         string
+		wantDays         []ReplayDay
+	}{
+		{
+			name:      "預設閾值",
+			threshold: 70, thresholdChanged: 35,
+			wantDays: []ReplayDay{{"2026-10-15", 8, 4}, {"2026-10-16", 2, 2}},
+		},
+		{
+			name:      "閾值調低_通知變多",
+			threshold: 40, thresholdChanged: 20,
+			wantDays: []ReplayDay{{"2026-10-15", 8, 6}, {"2026-10-16", 2, 2}},
+		},
+		{
+			name:      "閾值調高_只剩夜盤新高新低與定時提醒",
+			threshold: 100, thresholdChanged: 60,
+			wantDays: []ReplayDay{{"2026-10-15", 8, 3}, {"2026-10-16", 2, 0}},
+		},
+		{
+			name:      "休市日不重播",
+			threshold: 70, thresholdChanged: 35,
+			holidays: "2026-10-16",
+			wantDays: []ReplayDay{{"2026-10-15", 7, 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Threshold, cfg.ThresholdChanged, cfg.SpecialDates = tt.threshold, tt.thresholdChanged, tt.holidays
+
+			var log bytes.Buffer
+			got := Replay(cfg, ticks, &log)
+			if !strings.Contains(log.String(), "📊 加權指數") {
+				t.Errorf("Replay() log = %q, want evaluation messages", log.String())
+			}
+			if len(got.Days) != len(tt.wantDays) {
+				t.Fatalf("Replay() days = %+v, want %+v", got.Days, tt.wantDays)
+			}
+			total := 0
+			for i, want := range tt.wantDays {
+				if got.Days[i] != want {
+					t.Errorf("Replay() day[%d] = %+v, want %+v", i, got.Days[i], want)
+				}
+				total += want.Alerts
+			}
+			if got.Total() != total {
+				t.Errorf("Replay() total = %d, want %d", got.Total(), total)
+			}
+
+			var out bytes.Buffer
+			got.Print(&out, false)
+			if !strings.Contains(out.String(), "合計") {
+				t.Errorf("Print() = %q, want summary row", out.String())
+			}
+		})
+	}
+}
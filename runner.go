@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Runner 執行一次完整的檢查流程 (休市/盤別判斷 -> 抓取 -> 比對 -> 通知 -> 儲存)
+// 單次執行與常駐模式共用同一個 Runner；外部依賴皆為介面，測試時可替換
+type Runner struct {
+	Config   *Config
+	Clock    Clock
+	Sources  SourceLoader
+	Store    StateStore
+	Notifier Notifier
+	History  HistoryStore // 歷史紀錄 (nil 代表不記錄)
+	Indices  IndexSource  // 盤前簡報的美股指數 (nil 代表不附美股)
+	Retry    Backoff      // 盤前/夜盤期貨為 0 時的重試 (Attempts 含第一次抓取)
+	Out      io.Writer    // 過程訊息的輸出 (nil 代表標準輸出，重播等工具可關閉)
+}
+
+func NewRunner(cfg *Config, sources SourceLoader, store StateStore, notifier Notifier) *Runner {
+	return &Runner{
+		Config:   cfg,
+		Clock:    SystemClock{},
+		Sources:  sources,
+		Store:    store,
+		Notifier: notifier,
+		Retry:    Backoff{Attempts: 4, Base: 5 * time.Second, Max: 15 * time.Second}, // 第一次抓取 + 重試 3 次
+	}
+}
+
+// out 過程訊息的輸出
+func (r *Runner) out() io.Writer {
+	if r.Out == nil {
+		return os.Stdout
+	}
+	return r.Out
+}
+
+// RunOnce 執行一次檢查
+// 抓取失敗會記錄錯誤狀態並通知，不回傳 error；只有無法繼續運行的錯誤 (讀取狀態失敗等) 才回傳
+func (r *Runner) RunOnce(ctx context.Context) error {
+	cfg := r.Config
+
+	// 休市判斷
+	if IsTodayInDateList(r.Clock, cfg.SpecialDates, loc) {
+		fmt.Fprintln(r.out(), "☕ 今天是預設休市日，本次不檢查。")
+		return nil // 直接中斷
+	}
+
+	// --- 判斷盤別 ---
+	session, isTrading := GetSessionType(r.Clock, loc)
+	fmt.Fprintf(r.out(), "目前時段: %s, 是否交易中: %v\n", session, isTrading)
+
+	if !isTrading {
+		fmt.Fprintln(r.out(), "目前非監控時段，本次不檢查。")
+		// 收盤後的第一次執行發送該盤的收盤摘要；早盤開盤前發送盤前簡報
+		if err := r.summarize(ctx); err != nil {
+			return err
+		}
+		return r.brief(ctx)
+	}
+
+	// 讀取上次被通知時的價差
+	d, err := r.Store.Load(ctx)
+	if err != nil {
+		// 進入此處代表發生了「初始化客戶端失敗」或「讀取文件失敗（非不存在）」
+		// 這是無法運行業務邏輯的致命錯誤 (配置、權限、網路連線等)
+		return fmt.Errorf("狀態讀取發生致命錯誤，請檢查配置與權限: %w", err)
+	}
+
+	if DebugEnv == "1" || strings.ToUpper(DebugEnv) == "TRUE" {
+		fmt.Fprintf(r.out(), "%+v\n", *d) // DEBUG
+	}
+
+	// --- 執行爬蟲與錯誤狀態管理 ---
+	sources, err := r.Sources.Sources(ctx)
+	if err != nil {
+		return fmt.Errorf("無法建立報價來源: %w", err)
+	}
+	policy := cfg.ScrapePolicy()
+	policy.Clock = r.Clock
+	var refOK bool
+	policy.SpotRef, policy.FutureRef, refOK = d.PriceRefs(r.Clock.Now(), cfg.SpecialDates)
+	if !refOK && d.LastTWIIValue > 0 {
+		fmt.Fprintf(r.out(), "⚠️ 價格前值早於前一個交易日 (早盤: %s, 夜盤: %s)，本次不檢查報價偏離\n", d.TradingDay, d.NightTradingDay)
+	}
+	scrape := func() (ScrapeResult, error) {
+		ctx, cancel := context.WithTimeout(ctx, cfg.ScrapeDeadline)
+		defer cancel()
+		return ScrapeData(ctx, sources, policy)
+	}
+	scrapeFuture := func() (Quote, []string, error) {
+		ctx, cancel := context.WithTimeout(ctx, cfg.ScrapeDeadline)
+		defer cancel()
+		return ScrapeFuture(ctx, sources, policy)
+	}
+	startedAt := r.Clock.Now()
+	res, scrapeErr := scrape()
+	spotFallback := false
+	if scrapeErr != nil && res.Spot.Value == 0 && (IsTaipexPreOpen(r.Clock, loc) || session == SessionNight) {
+		if res.Future.Value == 0 { // 有機會爬到0
+			// 第一次抓取也算一次嘗試 (見 Backoff.Attempts)，之後最多再重試 Attempts-1 次
+			retry := r.Retry
+			retries := max(retry.Attempts, 1) - 1
+			for i := 1; i <= retries; i++ {
+				wait := retry.Delay(i)
+				fmt.Fprintf(r.out(), "⚠️ 盤前/夜盤期貨數值異常 (0), 等待 %s 後重試 (%d/%d)...\n", wait.Truncate(time.Millisecond), i, retries)
+				if err := Sleep(ctx, wait); err != nil { // 等一下再重試
+					scrapeErr = errors.Join(scrapeErr, fmt.Errorf("等待重試時中斷: %w", err))
+					break
+				}
+				// 只重試期貨，保留第一次抓取的提示 (來源不一致等)
+				future, notes, retryErr := scrapeFuture()
+				res.Future, scrapeErr = future, retryErr
+				res.Notes = append(res.Notes, notes...)
+				if res.Future.Value > 0 {
+					fmt.Fprintf(r.out(), "✅ 重試成功！取得期貨數值: %.2f\n", res.Future.Value)
+					break // 成功抓到，跳出迴圈
+				}
+			}
+		}
+		// 重試結束後的最終判斷
+		if res.Future.Value > 0 {
+			// 情況 A: 成功取得期貨 (或是原本就有，或是重試後拿到)
+			// 此時我們使用 "上次的加權指數" 來填補 spotVal (因為盤前/夜盤 spot 本來就是 0)，見 evaluate
+			spotFallback = true
+
+			// 重要：既然我們已經用 fallback 數據修復了，就應該清除錯誤
+			scrapeErr = nil
+		} else {
+			// 情況 B: 重試後期貨依然是 0
+			// 我們不 return，而是確保 scrapeErr 有值，讓後面的 CheckErrorState 處理
+			if scrapeErr == nil {
+				scrapeErr = fmt.Errorf("盤前/夜盤無法取得期貨報價 (數值為 0)")
+			}
+			// 程式繼續往下執行... -> 進到 CheckErrorState -> 記錄錯誤 -> 儲存 -> 發送 System Alert -> Exit
+		}
+	}
+
+	// 即使 ctx 已取消 (SIGTERM、期限將至)，仍要儲存狀態並發送通知 (已儲存的通知不會再發送)
+	saveCtx, cancelSave := persistContext(ctx)
+	defer cancelSave()
+
+	// 抓取成功就寫入歷史紀錄，與狀態能否儲存 (衝突、寫入失敗) 無關
+	// 是否發送行情通知在發送後補上，結束時才寫入
+	var tick *Tick
+	if scrapeErr == nil {
+		tick = r.newTick(d, session, res, spotFallback)
+		defer r.record(saveCtx, tick)
+	}
+
+	// 先儲存再通知: 與其他執行重疊時 (排程 + 手動、前一次執行太慢)，只有成功寫入的一方會發送通知
+	for attempt := 1; ; attempt++ {
+		e, err := r.evaluate(d, session, res, scrapeErr, spotFallback)
+		if err != nil {
+			return err
+		}
+		if !e.save {
+			return nil
+		}
+
+		d.LastUpdateTime = r.Clock.Now()
+		err = r.Store.Save(saveCtx, d)
+		if errors.Is(err, ErrStateConflict) {
+			latest, loadErr := r.Store.Load(saveCtx)
+			if loadErr != nil {
+				return fmt.Errorf("重新讀取狀態失敗: %w", loadErr)
+			}
+			// 對方在本次抓取之後才寫入，代表已處理過相同或更新的行情
+			if !latest.LastUpdateTime.Before(startedAt) || attempt >= maxConflictAttempts {
+				fmt.Fprintln(r.out(), "⚠️ 狀態已被其他執行更新，略過本次通知。")
+				return nil
+			}
+			fmt.Fprintln(r.out(), "⚠️ 狀態已被其他執行更新，以最新狀態重新判斷...")
+			d = latest
+			continue
+		}
+		if err != nil {
+			log.Printf("❌ %s失敗: %v", e.saveLabel, err)
+		} else {
+			fmt.Fprintf(r.out(), "✅ 已%s。\n", e.saveLabel)
+		}
+
+		// --- 發送 ---
+		if e.errorAlert != "" {
+			fmt.Fprintln(r.out(), "狀態改變，發送系統通知...")
+			r.Notifier.Notify(saveCtx, e.errorAlert)
+		}
+		if e.alert != "" {
+			fmt.Fprintln(r.out(), "觸發條件，發送 Telegram 通知...")
+			r.Notifier.Notify(saveCtx, e.alert)
+			if tick != nil {
+				tick.Alerted, tick.AlertReason = true, e.reason
+			}
+		}
+		return nil
+	}
+}
+
+// newTick 本次抓取的歷史紀錄
+// 盤前/夜盤的加權為前次收盤 (見 evaluate)；來源未提供合約代碼時以結算日曆推算 (見 CheckRollover)
+func (r *Runner) newTick(d *Data, session string, res ScrapeResult, spotFallback bool) *Tick {
+	if spotFallback {
+		res.Spot = d.fallbackSpot()
+	}
+	contract := res.Future.Contract
+	if contract == "" {
+		contract = NearMonthContract(r.Clock.Now(), loc)
+	}
+	return &Tick{
+		Time:         r.Clock.Now(),
+		Session:      session,
+		Spot:         res.Spot.Value,
+		Future:       res.Future.Value,
+		Diff:         res.Spot.Value - res.Future.Value,
+		SpotSource:   res.Spot.Source,
+		FutureSource: res.Future.Source,
+		Contract:     contract,
+	}
+}
+
+// record 寫入本次的歷史紀錄 (失敗只記錄 log，不影響本次檢查)
+func (r *Runner) record(ctx context.Context, t *Tick) {
+	if r.History == nil || t == nil {
+		return
+	}
+	if err := r.History.Append(ctx, *t); err != nil {
+		log.Printf("⚠️ 寫入歷史紀錄失敗: %v", err)
+	}
+}
+
+// notifyOnce 發送只需要一次的通知 (收盤摘要、盤前簡報)
+// 以狀態記錄已發送 (sent 檢查、mark 標記): 先儲存再通知，排程重複觸發或多個執行重疊時只會發送一次
+// prepare 在確認尚未發送後執行一次 (讀取歷史紀錄、外部報價等)；build 以最新狀態產生通知，回傳 false 代表不發送
+func (r *Runner) notifyOnce(ctx context.Context, label string, sent func(*Data) bool, mark func(*Data), prepare func(), build func(*Data) (string, bool)) error {
+	d, err := r.Store.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("狀態讀取發生致命錯誤，請檢查配置與權限: %w", err)
+	}
+	if sent(d) {
+		fmt.Fprintf(r.out(), "%s已發送。\n", label)
+		return nil
+	}
+	prepare()
+
+	saveCtx, cancelSave := persistContext(ctx)
+	defer cancelSave()
+
+	for attempt := 1; ; attempt++ {
+		msg, ok := build(d)
+		if !ok {
+			return nil
+		}
+
+		mark(d)
+		err := r.Store.Save(saveCtx, d)
+		if errors.Is(err, ErrStateConflict) {
+			latest, loadErr := r.Store.Load(saveCtx)
+			if loadErr != nil {
+				return fmt.Errorf("重新讀取狀態失敗: %w", loadErr)
+			}
+			if sent(latest) || attempt >= maxConflictAttempts {
+				fmt.Fprintf(r.out(), "⚠️ %s已由其他執行發送，略過本次通知。\n", label)
+				return nil
+			}
+			d = latest
+			continue
+		}
+		if err != nil {
+			// 無法記錄已發送時不通知，避免每次排程都重複發送
+			return fmt.Errorf("儲存%s狀態失敗: %w", label, err)
+		}
+
+		fmt.Fprintf(r.out(), "✅ 已儲存%s狀態，發送%s...\n", label, label)
+		r.Notifier.Notify(saveCtx, msg)
+		return nil
+	}
+}
+
+// 儲存狀態發生衝突時，最多重新判斷幾次
+const maxConflictAttempts = 3
+
+// 盤前/夜盤以前次加權補值時的來源名稱
+const spotFallbackSource = "前次加權"
+
+// fallbackSpot 盤前/夜盤以前次加權作為加權指數
+func (d *Data) fallbackSpot() Quote {
+	return Quote{Value: d.LastTWIIValue, Time: d.LastUpdateTime, Source: spotFallbackSource}
+}
+
+// evaluation 單次判斷的結果 (儲存成功後才發送通知)
+type evaluation struct {
+	errorAlert string // 系統異常/恢復通知
+	alert      string // 行情通知
+	save       bool   // 是否需要儲存狀態
+	saveLabel  string // 儲存的說明 (記錄用)
+	reason     string // 行情通知的原因 (不含來源說明，記錄於歷史紀錄)
+}
+
+// evaluate 以 d 的狀態判斷本次抓取結果，並更新 d
+// spotFallback 為 true 時 (盤前/夜盤) 以前次加權作為加權指數
+func (r *Runner) evaluate(d *Data, session string, res ScrapeResult, scrapeErr error, spotFallback bool) (evaluation, error) {
+	cfg := r.Config
+	var e evaluation
+
+	if spotFallback {
+		res.Spot = d.fallbackSpot()
+	}
+	spotVal, futureVal := res.Spot.Value, res.Future.Value
+	lastFuture := d.LastFutureValue // 前一次的期貨報價，CheckStale 會以本次報價覆寫
+
+	// 跨次執行的停滯檢查 (頁面快取或凍結時數值會一直相同)
+	// 夜盤與盤前的加權為前次收盤，現貨 13:30 收盤後也不再變動，只檢查期貨
+	var staleChanged bool
+	if scrapeErr == nil {
+		current := GetCurrentTime(r.Clock, loc)
+		checkSpot := session == SessionMorning && !IsTaipexPreOpen(r.Clock, loc) && current < 1330
+		staleChanged, scrapeErr = d.CheckStale(res, r.Clock.Now(), checkSpot, cfg.StaleAfter)
+	}
+
+	// 🎯 核心：使用 CheckErrorState 處理狀態變化 (正常<->失敗)
+	shouldAlertError, errorMsg := d.CheckErrorState(scrapeErr, r.Clock.Now())
+	if shouldAlertError {
+		e.errorAlert = errorMsg
+	}
+
+	// 發生錯誤後的處理：儲存錯誤狀態並退出
+	if scrapeErr != nil {
+		log.Printf("執行失敗: %v (Count: %d)", scrapeErr, d.ErrorCount)
+		d.addSessionStat(session, r.Clock.Now(), false, scrapeErr)
+		// ⚠️ 重要：即使失敗也要儲存，這樣下次才知道 ErrorCount > 0
+		e.save, e.saveLabel = true, "儲存錯誤狀態"
+		return e, nil // 結束本次檢查
+	}
+
+	// --- 以下為成功抓取後的正常業務邏輯 ---
+	// 此時 d.ErrorCount 已經被 CheckErrorState 重置為 0
+
+	fmt.Fprintf(r.out(), "📊 加權指數: %.2f (%s) | 台指期: %.2f (%s)\n", spotVal, res.Spot.Source, futureVal, res.Future.Source)
+	d.SetSources(res)
+
+	msg, err := NewMessage(session, r.out())
+	if err != nil {
+		return e, fmt.Errorf("無法判斷開盤階段%s", session)
+	}
+
+	var alertMsg string
+	var shouldNotify bool
+
+	// 新交易日的第一次執行: 先清除前一盤的高低點，才不會拿昨天的高低點判斷當日新高新低
+	reset := d.ResetSession(session, r.Clock.Now(), loc, lastFuture)
+	if reset {
+		fmt.Fprintf(r.out(), "🗓️ 新的交易時段 (%s)，重置高低點\n", session)
+	}
+
+	// 結算日轉倉時，新舊合約的月份價差會讓價差瞬間跳動，改發送轉倉通知
+	if rolloverMsg, isRollover := d.CheckRollover(res.Future.Contract, futureVal, r.Clock.Now(), loc); isRollover {
+		fmt.Fprintln(r.out(), "偵測到合約轉倉，抑制本次價差警示")
+		alertMsg, shouldNotify = rolloverMsg, true
+	} else {
+		alertMsg, shouldNotify = msg.Build(d, spotVal, futureVal, cfg.Threshold, cfg.ThresholdChanged)
+	}
+
+	// 判斷是否為關鍵時間
+	specificAlterMsg, isSpecificTime := CheckSpecificTimeAlert(r.Clock, loc)
+	if isSpecificTime {
+		shouldNotify = true
+		// 如果沒有符合觸發條件, 但是特定時間點依然發送, 要補上訊息
+		if alertMsg == "" {
+			alertMsg = msg.Info(specificAlterMsg, spotVal, futureVal)
+		}
+	}
+
+	if shouldNotify {
+		// 附上採用的來源，讓我們知道目前信任的是哪個報價
+		e.alert, e.reason = alertMsg+d.SourceInfo(), alertMsg
+		d.addSessionStat(session, r.Clock.Now(), true, nil)
+	}
+
+	shouldSave := d.UpdateDailyHighLow(spotVal, futureVal, session, spotFallback)
+	// 停滯計數與交易日需要跨次保存
+	shouldSave = shouldSave || staleChanged || reset
+
+	switch {
+	case shouldNotify:
+		e.save, e.saveLabel = true, "儲存當前數據作為下次比較的基準"
+	case shouldSave:
+		fmt.Fprintln(r.out(), "✅ 欄位資料異動，儲存新狀態...")
+		e.save, e.saveLabel = true, "儲存新狀態"
+	case shouldAlertError: // (這代表剛剛發生了 Recovery)
+		// 如果沒有觸發市場警報，但發生了系統狀態改變 (例如：Fail -> Normal Recovery)
+		// 必須儲存 d，以更新 ErrorCount=0 的狀態。
+		fmt.Fprintln(r.out(), "✅ 系統恢復，儲存新狀態...")
+		e.save, e.saveLabel = true, "儲存恢復狀態"
+	}
+
+	return e, nil
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingStore 記錄讀寫次數 (可模擬讀取失敗)
+type countingStore struct {
+	StateStore
+	loadErr error
+	onSave  func() // 儲存前呼叫 (模擬儲存途中發生的事)
+	loads   int
+	saves   int
+}
+
+func (s *countingStore) Load(ctx context.Context) (*Data, error) {
+	s.loads++
+	if s.loadErr != nil {
+		return nil, s.loadErr
+	}
+	return s.StateStore.Load(ctx)
+}
+
+func (s *countingStore) Save(ctx context.Context, d *Data) error {
+	s.saves++
+	if s.onSave != nil {
+		s.onSave()
+	}
+	return s.StateStore.Save(ctx, d)
+}
+
+// recordNotifier 記錄送出的通知
+type recordNotifier struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (n *recordNotifier) Notify(ctx context.Context, msg string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.msgs = append(n.msgs, msg)
+}
+
+// ctxNotifier 與 TelegramNotifier 相同，ctx 已取消時不會送出
+type ctxNotifier struct {
+	recordNotifier
+	dropped int
+}
+
+func (n *ctxNotifier) Notify(ctx context.Context, msg string) {
+	if ctx.Err() != nil {
+		n.mu.Lock()
+		n.dropped++
+		n.mu.Unlock()
+		return
+	}
+	n.recordNotifier.Notify(ctx, msg)
+}
+
+func newTestConfig() *Config {
+	return &Config{
+		Threshold:         70,
+		ThresholdChanged:  35,
+		QuoteQuorum:       1,
+		StaleAfter:        30 * time.Minute,
+		PriceMin:          1000,
+		PriceMax:          100000,
+		PriceMaxDeviation: 0.1,
+		ScrapeDeadline:    time.Second,
+	}
+}
+
+func TestRunner_RunOnce(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2026, 10, 16, hour, min, 0, 0, loc) // 週五
+	}
+	// 前次狀態: 加權 27000，價差 0 (高低點皆屬於當天)
+	prev := func() *Data {
+		return &Data{
+			LastTWIIValue: 27000,
+			TradingDay:    "2026-10-16",
+			SpotHigh:      27100, SpotLow: 26900,
+			FutureHigh: 27100, FutureLow: 26900,
+			NightTradingDay: "2026-10-16",
+			NightFutureHigh: 27100, NightFutureLow: 26900,
+		}
+	}
+	errDown := errors.New("連線逾時")
+	errPreOpen := errors.New("加權指數尚未開盤")
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		holidays string
+		data     *Data
+		source   *fakeSource
+		loadErr  error
+
+		wantErr        bool
+		wantLoads      int
+		wantAlerts     []string // 每則通知應包含的關鍵字 (依序)
+		wantSaves      int
+		wantErrorCount int
+		check          func(t *testing.T, d *Data) // 檢查儲存後的狀態
+	}{
+		{
+			name:     "休市日_不讀取狀態",
+			now:      at(10, 0),
+			holidays: "2026-10-16",
+			source:   &fakeSource{name: "fake", spot: 27010, future: 26900},
+		},
+		{
+			name:      "盤間休息_沒有紀錄_不發送收盤摘要",
+			now:       at(14, 0),
+			source:    &fakeSource{name: "fake", spot: 27010, future: 26900},
+			wantLoads: 1,
+		},
+		{
+			name:       "早盤逆價差過大_通知並儲存",
+			now:        at(10, 0),
+			data:       prev(),
+			source:     &fakeSource{name: "fake", spot: 27010, future: 26900},
+			wantLoads:  1,
+			wantAlerts: []string{"逆價差過大"},
+			wantSaves:  1,
+		},
+		{
+			name:      "早盤未達閾值_不通知也不儲存",
+			now:       at(10, 0),
+			data:      prev(),
+			source:    &fakeSource{name: "fake", spot: 27010, future: 27000},
+			wantLoads: 1,
+		},
+		{
+			name:       "盤前加權尚未開盤_以前次加權補值",
+			now:        at(8, 50),
+			data:       prev(),
+			source:     &fakeSource{name: "fake", spotErr: errPreOpen, future: 27100},
+			wantLoads:  1,
+			wantAlerts: []string{"加權: 27000.00"},
+			wantSaves:  1,
+		},
+		{
+			name:       "夜盤以早盤收盤補值",
+			now:        at(20, 0),
+			data:       prev(),
+			source:     &fakeSource{name: "fake", spotErr: ErrQuoteNotSupported, future: 27150},
+			wantLoads:  1,
+			wantAlerts: []string{"夜盤新高"},
+			wantSaves:  1,
+		},
+		{
+			name: "新交易日早盤首次執行_重置高低點",
+			now:  at(10, 0),
+			data: func() *Data {
+				d := prev()
+				d.TradingDay, d.SpotHigh = "2026-10-15", 27005 // 昨天的高點
+				return d
+			}(),
+			source:    &fakeSource{name: "fake", spot: 27010, future: 27000},
+			wantLoads: 1,
+			wantSaves: 1,
+			check: func(t *testing.T, d *Data) {
+				if d.TradingDay != "2026-10-16" || d.SpotHigh != 27010 || d.SpotLow != 27010 || d.FutureHigh != 27000 || d.FutureLow != 27000 {
+					t.Errorf("RunOnce() day high/low = %s (%.2f, %.2f, %.2f, %.2f), want reset to today's quotes",
+						d.TradingDay, d.SpotHigh, d.SpotLow, d.FutureHigh, d.FutureLow)
+				}
+			},
+		},
+		{
+			name: "新交易日夜盤首次執行_只重置夜盤高低點",
+			now:  at(20, 0),
+			data: func() *Data {
+				d := prev()
+				d.NightTradingDay, d.NightFutureHigh = "2026-10-15", 27020 // 前一個夜盤的高點
+				return d
+			}(),
+			source:    &fakeSource{name: "fake", spotErr: ErrQuoteNotSupported, future: 27030},
+			wantLoads: 1,
+			wantSaves: 1,
+			check: func(t *testing.T, d *Data) {
+				if d.NightTradingDay != "2026-10-16" || d.NightFutureHigh != 27030 || d.NightFutureLow != 27030 {
+					t.Errorf("RunOnce() night high/low = %s (%.2f, %.2f), want reset to 27030",
+						d.NightTradingDay, d.NightFutureHigh, d.NightFutureLow)
+				}
+				if d.FutureHigh != 27100 || d.FutureLow != 26900 {
+					t.Errorf("RunOnce() day future high/low = (%.2f, %.2f), want unchanged", d.FutureHigh, d.FutureLow)
+				}
+			},
+		},
+		{
+			name:           "夜盤期貨重試後仍失敗_記錄錯誤",
+			now:            at(20, 0),
+			data:           prev(),
+			source:         &fakeSource{name: "fake", spotErr: ErrQuoteNotSupported, futureErr: errDown},
+			wantLoads:      1,
+			wantAlerts:     []string{"系統異常"},
+			wantSaves:      1,
+			wantErrorCount: 1,
+		},
+		{
+			name:           "早盤抓取失敗_發送系統異常並儲存錯誤",
+			now:            at(10, 0),
+			data:           prev(),
+			source:         &fakeSource{name: "fake", spot: 27010, futureErr: errDown},
+			wantLoads:      1,
+			wantAlerts:     []string{"連線逾時"},
+			wantSaves:      1,
+			wantErrorCount: 1,
+		},
+		{
+			name: "恢復正常_發送恢復通知",
+			now:  at(10, 0),
+			data: func() *Data {
+				d := prev()
+				d.ErrorCount = 3
+				return d
+			}(),
+			source:     &fakeSource{name: "fake", spot: 27010, future: 27000},
+			wantLoads:  1,
+			wantAlerts: []string{"系統恢復"},
+			wantSaves:  1,
+		},
+		{
+			name:      "狀態讀取失敗_回傳錯誤",
+			now:       at(10, 0),
+			source:    &fakeSource{name: "fake", spot: 27010, future: 27000},
+			loadErr:   errDown,
+			wantErr:   true,
+			wantLoads: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.SpecialDates = tt.holidays
+			mem := NewMemoryStore()
+			if tt.data != nil {
+				mem.Save(context.Background(), tt.data)
+			}
+			store := &countingStore{StateStore: mem, loadErr: tt.loadErr}
+			notifier := &recordNotifier{}
+
+			r := NewRunner(cfg, StaticSources{tt.source}, store, notifier)
+			r.Clock = NewFakeClock(tt.now)
+			r.Retry = Backoff{Attempts: 2}
+
+			err := r.RunOnce(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunOnce() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if store.loads != tt.wantLoads {
+				t.Errorf("RunOnce() loads = %d, want %d", store.loads, tt.wantLoads)
+			}
+			if len(notifier.msgs) != len(tt.wantAlerts) {
+				t.Fatalf("RunOnce() alerts = %q, want %d alerts", notifier.msgs, len(tt.wantAlerts))
+			}
+			for i, want := range tt.wantAlerts {
+				if !strings.Contains(notifier.msgs[i], want) {
+					t.Errorf("RunOnce() alert[%d] = %q, want substring %q", i, notifier.msgs[i], want)
+				}
+			}
+			if store.saves != tt.wantSaves {
+				t.Errorf("RunOnce() saves = %d, want %d", store.saves, tt.wantSaves)
+			}
+			if saved, _ := mem.Load(context.Background()); tt.wantSaves > 0 && saved.ErrorCount != tt.wantErrorCount {
+				t.Errorf("RunOnce() saved ErrorCount = %d, want %d", saved.ErrorCount, tt.wantErrorCount)
+			}
+			if tt.check != nil {
+				saved, _ := mem.Load(context.Background())
+				tt.check(t, saved)
+			}
+		})
+	}
+}
+
+func TestRunner_RunOnce_Concurrent(t *testing.T) {
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, loc)
+	mem := NewMemoryStore()
+	mem.Save(context.Background(), &Data{
+		LastTWIIValue: 27000,
+		SpotHigh:      27100, SpotLow: 26900,
+		FutureHigh: 27100, FutureLow: 26900,
+	})
+	notifier := &recordNotifier{}
+	history := NewMemoryHistory()
+
+	// 抓取延遲讓兩次執行都在對方儲存前讀取狀態
+	source := &fakeSource{name: "fake", spot: 27010, future: 26900, spotDelay: 50 * time.Millisecond}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		r := NewRunner(newTestConfig(), StaticSources{source}, mem, notifier)
+		r.Clock = NewFakeClock(now)
+		r.History = history
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.RunOnce(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("RunOnce() #%d unexpected error: %v", i, err)
+		}
+	}
+	if len(notifier.msgs) != 1 {
+		t.Fatalf("RunOnce() alerts = %q, want exactly 1 alert", notifier.msgs)
+	}
+	if !strings.Contains(notifier.msgs[0], "逆價差過大") {
+		t.Errorf("RunOnce() alert = %q, want substring %q", notifier.msgs[0], "逆價差過大")
+	}
+
+	// 儲存衝突而放棄的執行也要記錄抓取結果，但只有發送通知的一方標記為已通知
+	ticks, err := history.Query(context.Background(), now, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Query() unexpected error: %v", err)
+	}
+	alerted := 0
+	for _, tick := range ticks {
+		if tick.Alerted {
+			alerted++
+		}
+	}
+	if len(ticks) != 2 || alerted != 1 {
+		t.Errorf("Query() = %+v, want 2 ticks with 1 alerted", ticks)
+	}
+}
+
+func TestRunner_RunOnce_History(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 10, 16, 10, 0, 0, 0, loc))
+	mem := NewMemoryStore()
+	mem.Save(context.Background(), &Data{LastTWIIValue: 27000, TradingDay: "2026-10-16", SpotHigh: 27100, SpotLow: 26900})
+	history := NewMemoryHistory()
+	source := &fakeSource{name: "fake", spot: 27010, future: 26900}
+
+	r := NewRunner(newTestConfig(), StaticSources{source}, mem, &recordNotifier{})
+	r.Clock = clock
+	r.History = history
+	r.Retry = Backoff{Attempts: 1}
+
+	// 1. 逆價差過大 -> 通知 2. 變動不大 -> 不通知 3. 抓取失敗 -> 不記錄
+	runs := []func(){
+		func() {},
+		func() { source.future = 26905 },
+		func() { source.futureErr = errors.New("連線逾時") },
+	}
+	for _, prepare := range runs {
+		prepare()
+		if err := r.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce() unexpected error: %v", err)
+		}
+		clock.Advance(time.Minute)
+	}
+
+	got, err := history.Query(context.Background(), time.Date(2026, 10, 16, 0, 0, 0, 0, loc), clock.Now())
+	if err != nil {
+		t.Fatalf("Query() unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Query() = %+v, want 2 ticks", got)
+	}
+	if first := got[0]; !first.Alerted || !strings.Contains(first.AlertReason, "逆價差過大") ||
+		first.Spot != 27010 || first.Future != 26900 || first.Diff != 110 || first.Session != SessionMorning || first.FutureSource != "fake" {
+		t.Errorf("Query()[0] = %+v, want alerted tick", first)
+	}
+	if second := got[1]; second.Alerted || second.AlertReason != "" || second.Future != 26905 {
+		t.Errorf("Query()[1] = %+v, want tick without alert", second)
+	}
+}
+
+func TestRunner_RunOnce_SpotClosedNotStale(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 10, 16, 13, 28, 0, 0, loc))
+	mem := NewMemoryStore()
+	mem.Save(context.Background(), &Data{LastTWIIValue: 27000, TradingDay: "2026-10-16", SpotHigh: 27100, SpotLow: 26900})
+	notifier := &recordNotifier{}
+	source := &fakeSource{name: "fake", spot: 27000, future: 27000}
+
+	cfg := newTestConfig()
+	cfg.StaleAfter = 3 * time.Minute
+	r := NewRunner(cfg, StaticSources{source}, mem, notifier)
+	r.Clock = clock
+	r.Retry = Backoff{Attempts: 1}
+
+	// 常駐模式每 30 秒執行: 現貨 13:30 收盤後加權不再變動，期貨照常變動到 13:45
+	for i := 0; !clock.Now().After(time.Date(2026, 10, 16, 13, 45, 0, 0, loc)); i++ {
+		source.future = 27000 + float64(i%3)
+		if err := r.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce() at %v unexpected error: %v", clock.Now(), err)
+		}
+		clock.Advance(30 * time.Second)
+	}
+
+	for _, msg := range notifier.msgs {
+		if strings.Contains(msg, "報價資料過期") {
+			t.Errorf("RunOnce() alert = %q, want no stale alert after the spot close", msg)
+		}
+	}
+}
+
+func TestRunner_RunOnce_StaleReference(t *testing.T) {
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, loc) // 週五
+
+	tests := []struct {
+		name      string
+		day       string // 前值所屬的交易日
+		wantAlert string
+		wantSpot  float64 // 儲存的加權前值
+	}{
+		{name: "前值為前一個交易日_偏離過大視為異常", day: "2026-10-15", wantAlert: "報價數值異常", wantSpot: 20000},
+		// 停機一週期間累積上漲 15%，前值已不能作為偏離的基準
+		{name: "停機數日_不檢查偏離並更新前值", day: "2026-10-08", wantSpot: 23000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mem := NewMemoryStore()
+			mem.Save(context.Background(), &Data{
+				LastTWIIValue: 20000, LastFutureValue: 20000,
+				TradingDay: tt.day, NightTradingDay: tt.day,
+				SpotHigh: 20000, SpotLow: 20000, FutureHigh: 20000, FutureLow: 20000,
+			})
+			notifier := &recordNotifier{}
+
+			r := NewRunner(newTestConfig(), StaticSources{&fakeSource{name: "fake", spot: 23000, future: 23000}}, mem, notifier)
+			r.Clock = NewFakeClock(now)
+			r.Retry = Backoff{Attempts: 1}
+			if err := r.RunOnce(context.Background()); err != nil {
+				t.Fatalf("RunOnce() unexpected error: %v", err)
+			}
+
+			alerted := false
+			for _, msg := range notifier.msgs {
+				if tt.wantAlert != "" && strings.Contains(msg, tt.wantAlert) {
+					alerted = true
+				}
+				if tt.wantAlert == "" && strings.Contains(msg, "系統異常") {
+					t.Errorf("RunOnce() alert = %q, want no error alert", msg)
+				}
+			}
+			if tt.wantAlert != "" && !alerted {
+				t.Errorf("RunOnce() alerts = %q, want substring %q", notifier.msgs, tt.wantAlert)
+			}
+			if saved, _ := mem.Load(context.Background()); saved.LastTWIIValue != tt.wantSpot {
+				t.Errorf("RunOnce() saved LastTWIIValue = %.2f, want %.2f", saved.LastTWIIValue, tt.wantSpot)
+			}
+		})
+	}
+}
+
+// countFutureSource 記錄期貨被抓取的次數
+type countFutureSource struct {
+	*fakeSource
+	futureCalls int
+}
+
+func (s *countFutureSource) FetchFuture(ctx context.Context) (Quote, error) {
+	s.futureCalls++
+	return s.fakeSource.FetchFuture(ctx)
+}
+
+func TestRunner_RunOnce_FutureRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		attempts  int
+		wantCalls int // 期貨抓取次數 (含第一次)
+	}{
+		{name: "不重試", attempts: 1, wantCalls: 1},
+		{name: "嘗試3次", attempts: 3, wantCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 夜盤期貨持續抓取失敗
+			source := &countFutureSource{fakeSource: &fakeSource{name: "fake", futureErr: errors.New("連線逾時"), spotErr: errors.New("加權指數已收盤")}}
+			r := NewRunner(newTestConfig(), StaticSources{source}, NewMemoryStore(), &recordNotifier{})
+			r.Clock = NewFakeClock(time.Date(2026, 10, 16, 20, 0, 0, 0, loc))
+			r.Retry = Backoff{Attempts: tt.attempts}
+
+			if err := r.RunOnce(context.Background()); err != nil {
+				t.Fatalf("RunOnce() unexpected error: %v", err)
+			}
+			if source.futureCalls != tt.wantCalls {
+				t.Errorf("FetchFuture() calls = %d, want %d", source.futureCalls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRunner_RunOnce_CancelledScrape(t *testing.T) {
+	mem := NewMemoryStore()
+	mem.Save(context.Background(), &Data{LastTWIIValue: 27000, TradingDay: "2026-10-16"})
+	notifier := &ctxNotifier{}
+
+	// 來源一直等到 ctx 取消才回應 (模擬抓取途中收到 SIGTERM)
+	source := &fakeSource{name: "slow", spot: 27010, future: 27000, spotDelay: time.Hour, futureDelay: time.Hour}
+	cfg := newTestConfig()
+	cfg.ScrapeDeadline = time.Hour
+	r := NewRunner(cfg, StaticSources{source}, mem, notifier)
+	r.Clock = NewFakeClock(time.Date(2026, 10, 16, 10, 0, 0, 0, loc))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if err := r.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce() unexpected error: %v", err)
+	}
+
+	saved, _ := mem.Load(context.Background())
+	if saved.ErrorCount != 1 || !strings.Contains(saved.LastError, "context canceled") {
+		t.Errorf("RunOnce() saved error state = (%d, %q), want (1, context canceled)", saved.ErrorCount, saved.LastError)
+	}
+	if len(notifier.msgs) != 1 || !strings.Contains(notifier.msgs[0], "系統異常") {
+		t.Errorf("RunOnce() alerts = %q, want 1 error alert", notifier.msgs)
+	}
+}
+
+func TestRunner_RunOnce_CancelledAfterSave(t *testing.T) {
+	mem := NewMemoryStore()
+	mem.Save(context.Background(), &Data{
+		LastTWIIValue: 27000, TradingDay: "2026-10-16",
+		SpotHigh: 27100, SpotLow: 26900, FutureHigh: 27100, FutureLow: 26900,
+	})
+	notifier := &ctxNotifier{}
+
+	// 儲存途中收到 SIGTERM: 狀態已記錄為通知過，通知必須送出，否則這次行情不會再通知
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &countingStore{StateStore: mem, onSave: cancel}
+	r := NewRunner(newTestConfig(), StaticSources{&fakeSource{name: "fake", spot: 27010, future: 26900}}, store, notifier)
+	r.Clock = NewFakeClock(time.Date(2026, 10, 16, 10, 0, 0, 0, loc))
+
+	if err := r.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce() unexpected error: %v", err)
+	}
+	if store.saves != 1 {
+		t.Errorf("RunOnce() saves = %d, want 1", store.saves)
+	}
+	if notifier.dropped != 0 || len(notifier.msgs) != 1 || !strings.Contains(notifier.msgs[0], "逆價差過大") {
+		t.Errorf("RunOnce() alerts = %q (dropped %d), want 1 market alert", notifier.msgs, notifier.dropped)
+	}
+}
+
+func TestRunner_RunOnce_PrevSessionClose(t *testing.T) {
+	mem := NewMemoryStore()
+	// 早盤最後一次報價 27050
+	mem.Save(context.Background(), &Data{
+		LastTWIIValue: 27000, LastFutureValue: 27050,
+		TradingDay: "2026-10-16", SpotHigh: 27100, SpotLow: 26900,
+		FutureHigh: 27100, FutureLow: 26900,
+		NightTradingDay: "2026-10-15", NightFutureHigh: 27150, NightFutureLow: 26950,
+	})
+	notifier := &recordNotifier{}
+
+	// 夜盤第一次報價 27200: 前一盤收盤為早盤的最後報價，不是本次報價
+	source := &fakeSource{name: "fake", spot: 27000, future: 27200}
+	r := NewRunner(newTestConfig(), StaticSources{source}, mem, notifier)
+	r.Clock = NewFakeClock(time.Date(2026, 10, 16, 15, 0, 0, 0, loc))
+
+	if err := r.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() unexpected error: %v", err)
+	}
+
+	saved, _ := mem.Load(context.Background())
+	if saved.PrevSession != SessionMorning || saved.PrevClose != 27050 || saved.PrevHigh != 27100 || saved.PrevLow != 26900 {
+		t.Errorf("RunOnce() prev session = %s %.2f (%.2f ~ %.2f), want %s 27050.00 (26900.00 ~ 27100.00)",
+			saved.PrevSession, saved.PrevClose, saved.PrevLow, saved.PrevHigh, SessionMorning)
+	}
+	if saved.NightTradingDay != "2026-10-16" || saved.LastFutureValue != 27200 {
+		t.Errorf("RunOnce() night day/last future = (%s, %.2f), want (2026-10-16, 27200.00)", saved.NightTradingDay, saved.LastFutureValue)
+	}
+}
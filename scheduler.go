@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// 常駐模式 (--daemon) 的排程
+// 取代 Cloud Scheduler 的兩組 cron (週一至週五每 5 分鐘、週六 00:00 ~ 05:00 每 5 分鐘)，
+// 盤中每 PollInterval 檢查一次，收盤後 (收盤摘要) 與早盤開盤前 (盤前簡報) 各執行一次，其餘時間睡到下一個盤別開盤
+
+// 各盤別開盤時間 (時, 分)
+var sessionOpens = [][2]int{{8, 45}, {15, 0}}
+
+// Schedule 常駐模式的檢查時間表
+type Schedule struct {
+	Interval time.Duration // 盤中檢查間隔
+	Holidays string        // 特殊休市日 (同 SPECIAL_DATES)
+}
+
+// Active 指定時間是否需要檢查
+// 週一至週五的早盤與夜盤，以及週五夜盤延續到週六 05:00 的部分
+func (s Schedule) Active(t time.Time) bool {
+	t = t.In(loc)
+	if _, isTrading := SessionAt(t, loc); !isTrading {
+		return false
+	}
+	switch t.Weekday() {
+	case time.Sunday:
+		return false
+	case time.Saturday:
+		if t.Hour()*100+t.Minute() > 500 {
+			return false
+		}
+	}
+	return !isDateInList(s.Holidays, t)
+}
+
+// SummaryDue 指定時間是否為收盤摘要的時間 (開盤時有在檢查的盤別，收盤後的第一分鐘)
+func (s Schedule) SummaryDue(t time.Time) bool {
+	session, open, ok := lastClosedSession(t)
+	if !ok || !s.Active(open) {
+		return false
+	}
+	end := sessionEnd(session, open)
+	return !t.Before(end) && t.Before(end.Add(time.Minute))
+}
+
+// BriefingDue 指定時間是否為盤前簡報的時間 (有早盤的日子，開盤前 briefingLead 的第一分鐘)
+func (s Schedule) BriefingDue(t time.Time) bool {
+	open := morningOpen(t)
+	at := open.Add(-briefingLead)
+	return !t.Before(at) && t.Before(at.Add(time.Minute)) && s.Active(open)
+}
+
+// Next 下一次檢查的時間
+// 盤中對齊 Interval 的整數倍 (例如 30s -> 每分鐘的 :00 與 :30)，否則為收盤摘要、盤前簡報或下一個開盤時間
+func (s Schedule) Next(now time.Time) time.Time {
+	now = now.In(loc)
+	if s.Interval > 0 {
+		if next := now.Truncate(s.Interval).Add(s.Interval); s.Active(next) {
+			return next
+		}
+	}
+
+	// 最長的連假 (春節) 也不會超過兩週；從前一天開始，才會包含跨日夜盤的收盤
+	var next time.Time
+	for i := -1; i <= 14 && next.IsZero(); i++ {
+		for _, hm := range sessionOpens {
+			open := time.Date(now.Year(), now.Month(), now.Day()+i, hm[0], hm[1], 0, 0, loc)
+			if !s.Active(open) {
+				continue
+			}
+			session, _ := SessionAt(open, loc)
+			candidates := []time.Time{open, sessionEnd(session, open)}
+			if session == SessionMorning {
+				candidates = append(candidates, open.Add(-briefingLead))
+			}
+			for _, t := range candidates {
+				if t.After(now) && (next.IsZero() || t.Before(next)) {
+					next = t
+				}
+			}
+		}
+	}
+	if next.IsZero() {
+		return now.Add(time.Hour)
+	}
+	return next
+}
+
+// RunDaemon 常駐執行，直到 ctx 取消 (SIGTERM)
+// 每次檢查共用 Runner (HTTP 連線與 Firestore 客戶端)，並各自套用 TASK_TIMEOUT 期限
+func RunDaemon(ctx context.Context, r *Runner, cfg *Config) {
+	s := Schedule{Interval: cfg.PollInterval, Holidays: cfg.SpecialDates}
+	fmt.Printf("常駐模式啟動，盤中每 %s 檢查一次\n", cfg.PollInterval)
+
+	for {
+		if now := r.Clock.Now(); s.Active(now) || s.SummaryDue(now) || s.BriefingDue(now) {
+			runCtx, cancel := runContext(ctx, cfg)
+			if err := r.RunOnce(runCtx); err != nil {
+				log.Printf("❌ 本次檢查失敗: %v", err)
+			}
+			cancel()
+		}
+
+		now := r.Clock.Now()
+		next := s.Next(now)
+		wait := next.Sub(now)
+		if wait > cfg.PollInterval {
+			fmt.Printf("非監控時段，下次檢查: %s\n", next.Format("01-02 15:04:05"))
+		}
+		if err := Sleep(ctx, wait); err != nil {
+			fmt.Println("收到結束訊號，常駐模式結束。")
+			return
+		}
+	}
+}
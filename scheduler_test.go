@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	at := func(day, hour, min, sec int) time.Time {
+		return time.Date(2026, 10, day, hour, min, sec, 0, loc) // 2026-10-16 為週五
+	}
+
+	tests := []struct {
+		name     string
+		holidays string
+		now      time.Time
+		want     time.Time
+	}{
+		{"早盤中_對齊間隔", "", at(16, 9, 0, 10), at(16, 9, 0, 30)},
+		{"早盤收盤_先發送收盤摘要", "", at(16, 13, 45, 40), at(16, 13, 46, 0)},
+		{"收盤摘要後_睡到夜盤開盤", "", at(16, 13, 46, 0), at(16, 15, 0, 0)},
+		{"夜盤收盤_睡到盤前簡報", "", at(16, 5, 10, 0), at(16, 8, 30, 0)},
+		{"盤前簡報後_睡到早盤開盤", "", at(16, 8, 30, 0), at(16, 8, 45, 0)},
+		{"週六凌晨夜盤收尾", "", at(17, 4, 59, 50), at(17, 5, 0, 0)},
+		{"週六夜盤結束_先發送收盤摘要", "", at(17, 5, 0, 40), at(17, 5, 1, 0)},
+		{"週六收盤摘要後_睡到週一盤前簡報", "", at(17, 5, 1, 0), at(19, 8, 30, 0)},
+		{"週日_睡到週一盤前簡報", "", at(18, 12, 0, 0), at(19, 8, 30, 0)},
+		{"週一休市_睡到週二盤前簡報", "2026-10-19", at(17, 5, 1, 0), at(20, 8, 30, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Schedule{Interval: 30 * time.Second, Holidays: tt.holidays}
+			if got := s.Next(tt.now); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedule_Active(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"週五早盤", time.Date(2026, 10, 16, 10, 0, 0, 0, loc), true},
+		{"週五盤間休息", time.Date(2026, 10, 16, 14, 0, 0, 0, loc), false},
+		{"週六凌晨夜盤", time.Date(2026, 10, 17, 2, 0, 0, 0, loc), true},
+		{"週六早上", time.Date(2026, 10, 17, 9, 0, 0, 0, loc), false},
+		{"週日晚上", time.Date(2026, 10, 18, 20, 0, 0, 0, loc), false},
+	}
+
+	s := Schedule{Interval: time.Minute}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Active(tt.now); got != tt.want {
+				t.Errorf("Active(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedule_SummaryDue(t *testing.T) {
+	tests := []struct {
+		name     string
+		holidays string
+		now      time.Time
+		want     bool
+	}{
+		{"早盤收盤後", "", time.Date(2026, 10, 16, 13, 46, 0, 0, loc), true},
+		{"早盤收盤後_已超過一分鐘", "", time.Date(2026, 10, 16, 13, 50, 0, 0, loc), false},
+		{"週六夜盤收盤後", "", time.Date(2026, 10, 17, 5, 1, 0, 0, loc), true},
+		{"週一凌晨_週日沒有夜盤", "", time.Date(2026, 10, 19, 5, 1, 0, 0, loc), false},
+		{"休市日", "2026-10-16", time.Date(2026, 10, 16, 13, 46, 0, 0, loc), false},
+		{"盤中", "", time.Date(2026, 10, 16, 10, 0, 0, 0, loc), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Schedule{Interval: time.Minute, Holidays: tt.holidays}
+			if got := s.SummaryDue(tt.now); got != tt.want {
+				t.Errorf("SummaryDue(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedule_BriefingDue(t *testing.T) {
+	tests := []struct {
+		name     string
+		holidays string
+		now      time.Time
+		want     bool
+	}{
+		{"週五開盤前", "", time.Date(2026, 10, 16, 8, 30, 0, 0, loc), true},
+		{"週五開盤前_已超過一分鐘", "", time.Date(2026, 10, 16, 8, 35, 0, 0, loc), false},
+		{"週六沒有早盤", "", time.Date(2026, 10, 17, 8, 30, 0, 0, loc), false},
+		{"休市日", "2026-10-16", time.Date(2026, 10, 16, 8, 30, 0, 0, loc), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Schedule{Interval: time.Minute, Holidays: tt.holidays}
+			if got := s.BriefingDue(tt.now); got != tt.want {
+				t.Errorf("BriefingDue(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/antchfx/xpath"
+)
+
+// Selectors 爬蟲頁面與節點設定
+// 載入順序 (後者覆蓋前者): 預設值 -> SELECTORS_FILE -> 環境變數 -> Firestore 覆寫文件
+type Selectors struct {
+	SpotURL       string   `json:"spot_url" firestore:"spot_url"`
+	SpotXPath     string   `json:"spot_xpath" firestore:"spot_xpath"`
+	SpotFallbacks []string `json:"spot_fallbacks" firestore:"spot_fallbacks"` // 候選 XPath
+	SpotCSS       []string `json:"spot_css" firestore:"spot_css"`             // 候選 CSS selector
+
+	FutureURL       string   `json:"future_url" firestore:"future_url"`
+	FutureXPath     string   `json:"future_xpath" firestore:"future_xpath"`
+	FutureFallbacks []string `json:"future_fallbacks" firestore:"future_fallbacks"`
+	FutureCSS       []string `json:"future_css" firestore:"future_css"`
+}
+
+// DefaultSelectors Yahoo 股市的預設設定
+func DefaultSelectors() Selectors {
+	return Selectors{
+		SpotURL:         DefaultSpotURL,
+		SpotXPath:       DefaultSpotXPath,
+		SpotFallbacks:   DefaultSpotFallbackXPaths,
+		FutureURL:       DefaultFutureURL,
+		FutureXPath:     DefaultFutureXPath,
+		FutureFallbacks: DefaultFutureFallbackXPaths,
+	}
+}
+
+// Merge 以 o 中非空的欄位覆蓋目前設定
+func (s *Selectors) Merge(o Selectors) {
+	mergeString := func(dst *string, v string) {
+		if strings.TrimSpace(v) != "" {
+			*dst = strings.TrimSpace(v)
+		}
+	}
+	mergeList := func(dst *[]string, v []string) {
+		if len(v) > 0 {
+			*dst = v
+		}
+	}
+
+	mergeString(&s.SpotURL, o.SpotURL)
+	mergeString(&s.SpotXPath, o.SpotXPath)
+	mergeList(&s.SpotFallbacks, o.SpotFallbacks)
+	mergeList(&s.SpotCSS, o.SpotCSS)
+	mergeString(&s.FutureURL, o.FutureURL)
+	mergeString(&s.FutureXPath, o.FutureXPath)
+	mergeList(&s.FutureFallbacks, o.FutureFallbacks)
+	mergeList(&s.FutureCSS, o.FutureCSS)
+}
+
+// SpotCandidates 加權的候選 XPath (候選 XPath + CSS selector 轉換結果)
+func (s *Selectors) SpotCandidates() []string {
+	return candidates(s.SpotFallbacks, s.SpotCSS)
+}
+
+// FutureCandidates 期貨的候選 XPath (候選 XPath + CSS selector 轉換結果)
+func (s *Selectors) FutureCandidates() []string {
+	return candidates(s.FutureFallbacks, s.FutureCSS)
+}
+
+func candidates(xpaths, css []string) []string {
+	list := append([]string{}, xpaths...)
+	for _, sel := range css {
+		// 設定載入時已經驗證過，這裡忽略錯誤
+		if expr, err := CSSToXPath(sel); err == nil {
+			list = append(list, expr)
+		}
+	}
+	return list
+}
+
+// Validate 檢查 URL 與 XPath / CSS selector 語法
+func (s *Selectors) Validate() error {
+	checkURL := func(name, v string) error {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s 不是有效的網址: %q", name, v)
+		}
+		return nil
+	}
+	checkXPath := func(name, v string) error {
+		if _, err := xpath.Compile(v); err != nil {
+			return fmt.Errorf("%s XPath 語法錯誤 %q: %v", name, v, err)
+		}
+		return nil
+	}
+
+	checks := []error{
+		checkURL("spot_url", s.SpotURL),
+		checkXPath("spot_xpath", s.SpotXPath),
+		checkURL("future_url", s.FutureURL),
+		checkXPath("future_xpath", s.FutureXPath),
+	}
+	for _, v := range append(append([]string{}, s.SpotFallbacks...), s.FutureFallbacks...) {
+		checks = append(checks, checkXPath("fallbacks", v))
+	}
+	for _, v := range append(append([]string{}, s.SpotCSS...), s.FutureCSS...) {
+		if _, err := CSSToXPath(v); err != nil {
+			checks = append(checks, fmt.Errorf("css selector 語法錯誤 %q: %v", v, err))
+		}
+	}
+
+	for _, err := range checks {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadSelectors 依序載入預設值、SELECTORS_FILE 與環境變數並驗證
+// XPath 常含有逗號，因此不透過 osenv (會以逗號切割) 而直接讀取環境變數
+func LoadSelectors() (Selectors, error) {
+	s := DefaultSelectors()
+
+	if path := os.Getenv("SELECTORS_FILE"); path != "" {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return s, fmt.Errorf("讀取 SELECTORS_FILE 失敗: %w", err)
+		}
+		var file Selectors
+		if err := json.Unmarshal(body, &file); err != nil {
+			return s, fmt.Errorf("解析 SELECTORS_FILE 失敗: %w", err)
+		}
+		s.Merge(file)
+	}
+
+	s.Merge(Selectors{
+		SpotURL:     os.Getenv("SPOT_URL"),
+		SpotXPath:   os.Getenv("SPOT_XPATH"),
+		FutureURL:   os.Getenv("FUTURE_URL"),
+		FutureXPath: os.Getenv("FUTURE_XPATH"),
+	})
+
+	if err := s.Validate(); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// CSSToXPath 將簡單的 CSS selector 轉換為 XPath
+// 支援: 標籤、#id、.class、[attr]、[attr=value]、子代 (>) 與後代 (空白) 組合
+// class 名稱中的特殊字元需以反斜線跳脫，例如 Yahoo 的 .Fz\(32px\)
+func CSSToXPath(sel string) (string, error) {
+	sel = strings.TrimSpace(sel)
+	if sel == "" {
+		return "", fmt.Errorf("空的 selector")
+	}
+
+	var b strings.Builder
+	b.WriteString("//")
+
+	i := 0
+	// readIdent 讀取識別字 (處理反斜線跳脫)
+	readIdent := func() string {
+		var id strings.Builder
+		for i < len(sel) {
+			c := sel[i]
+			if c == '\\' && i+1 < len(sel) {
+				id.WriteByte(sel[i+1])
+				i += 2
+				continue
+			}
+			if c == '#' || c == '.' || c == '[' || c == ']' || c == '>' || c == ' ' || c == '=' {
+				break
+			}
+			id.WriteByte(c)
+			i++
+		}
+		return id.String()
+	}
+
+	compound := true // 目前位置是否為新的複合選擇器開頭
+	for i < len(sel) {
+		c := sel[i]
+		switch {
+		case c == ' ' || c == '>':
+			// 組合子
+			child := false
+			for i < len(sel) && (sel[i] == ' ' || sel[i] == '>') {
+				if sel[i] == '>' {
+					child = true
+				}
+				i++
+			}
+			if child {
+				b.WriteString("/")
+			} else {
+				b.WriteString("//")
+			}
+			compound = true
+		case c == '#':
+			i++
+			if compound {
+				b.WriteString("*")
+			}
+			fmt.Fprintf(&b, "[@id='%s']", readIdent())
+			compound = false
+		case c == '.':
+			i++
+			if compound {
+				b.WriteString("*")
+			}
+			fmt.Fprintf(&b, "[contains(concat(' ', normalize-space(@class), ' '), ' %s ')]", readIdent())
+			compound = false
+		case c == '[':
+			i++
+			if compound {
+				b.WriteString("*")
+			}
+			end := strings.IndexByte(sel[i:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("缺少 ']'")
+			}
+			attr := sel[i : i+end]
+			i += end + 1
+			if name, val, ok := strings.Cut(attr, "="); ok {
+				val = strings.Trim(val, `"'`)
+				fmt.Fprintf(&b, "[@%s='%s']", strings.TrimSpace(name), val)
+			} else {
+				fmt.Fprintf(&b, "[@%s]", strings.TrimSpace(attr))
+			}
+			compound = false
+		default:
+			if !compound {
+				return "", fmt.Errorf("無法解析位置 %d 的 %q", i, string(c))
+			}
+			tag := readIdent()
+			if tag == "" {
+				return "", fmt.Errorf("無法解析位置 %d 的 %q", i, string(c))
+			}
+			b.WriteString(tag)
+			compound = false
+		}
+	}
+
+	if compound {
+		return "", fmt.Errorf("selector 不完整")
+	}
+
+	expr := b.String()
+	if _, err := xpath.Compile(expr); err != nil {
+		return "", err
+	}
+	return expr, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCSSToXPath(t *testing.T) {
+	body, err := os.ReadFile("testdata/yahoo_spot.html")
+	if err != nil {
+		t.Fatalf("讀取測試資料失敗: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		selector string
+		wantText string // 空字串代表預期轉換失敗
+	}{
+		{"id_後代_class", `#main-0-QuoteHeader-Proxy span.Fz\(32px\)`, "27,201.64"},
+		{"子代組合", `div.Ai\(fe\) > span`, "27,201.64"},
+		{"屬性", `[id=main-0-QuoteHeader-Proxy] h1`, "加權指數"},
+		{"多個class", `span.Fz\(20px\).Fw\(b\)`, "67.71"},
+		{"屬性缺少結尾", `div[id`, ""},
+		{"組合子結尾", `div >`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := CSSToXPath(tt.selector)
+			if tt.wantText == "" {
+				if err == nil {
+					t.Errorf("CSSToXPath(%q) = %v, want error", tt.selector, expr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CSSToXPath(%q) unexpected error: %v", tt.selector, err)
+			}
+
+			got, err := FindValueString(body, expr)
+			if err != nil || got != tt.wantText {
+				t.Errorf("FindValueString(%s) = (%q, %v), want %q", expr, got, err, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestLoadSelectors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "selectors.json")
+	os.WriteFile(file, []byte(`{
+		"spot_xpath": "//span[contains(@class,'Fz(32px)')]",
+		"spot_css": ["span.Fz\\(32px\\)"],
+		"future_url": "https://example.com/futures"
+	}`), 0o644)
+
+	t.Setenv("SELECTORS_FILE", file)
+	t.Setenv("SPOT_URL", "")
+	t.Setenv("SPOT_XPATH", "")
+	t.Setenv("FUTURE_XPATH", "")
+	// 環境變數優先於設定檔
+	t.Setenv("FUTURE_URL", "https://example.com/futures-env")
+
+	s, err := LoadSelectors()
+	if err != nil {
+		t.Fatalf("LoadSelectors() unexpected error: %v", err)
+	}
+
+	if s.SpotURL != DefaultSpotURL {
+		t.Errorf("SpotURL = %v, want default", s.SpotURL)
+	}
+	if s.SpotXPath != "//span[contains(@class,'Fz(32px)')]" {
+		t.Errorf("SpotXPath = %v, want value from file", s.SpotXPath)
+	}
+	if s.FutureURL != "https://example.com/futures-env" {
+		t.Errorf("FutureURL = %v, want value from env", s.FutureURL)
+	}
+	if got := len(s.SpotCandidates()); got != len(DefaultSpotFallbackXPaths)+1 {
+		t.Errorf("SpotCandidates() len = %d, want fallbacks + css", got)
+	}
+
+	// 無效的 XPath 應在啟動時被擋下
+	t.Setenv("SPOT_XPATH", "//span[")
+	if _, err := LoadSelectors(); err == nil {
+		t.Errorf("LoadSelectors() with invalid xpath want error")
+	}
+}
+
+func TestXPathSource_Fallback(t *testing.T) {
+	srv := newFixtureServer(t, "testdata/yahoo_spot.html")
+
+	sel := DefaultSelectors()
+	sel.SpotURL = srv.URL
+	sel.SpotXPath = "//span[@id='layout-changed']"
+	sel.SpotFallbacks = nil
+	sel.SpotCSS = []string{`span.Fz\(32px\)`}
+
+	q, err := NewYahooSource(sel, newTestFetcher()).FetchSpot(context.Background())
+	if err != nil {
+		t.Fatalf("FetchSpot() unexpected error: %v", err)
+	}
+	if q.Value != 27201.64 || q.Raw != "27,201.64" {
+		t.Errorf("FetchSpot() = %+v, want 27201.64 from css fallback", q)
+	}
+}
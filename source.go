@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+// Quote 單一報價 (數值 + 報價時間)
+type Quote struct {
+	Value    float64
+	Time     time.Time // 報價時間 (來源未提供時為抓取時間)
+	Source   string    // 報價來源名稱
+	Contract string    // 期貨合約代碼 (例如: TXFK5)，來源未提供時為空字串
+	Raw      string    // 來源的原始字串 (除錯用)
+
+	FetchedAt time.Time // 實際取得報價的時間 (由 ScrapeData 記錄)
+}
+
+// ErrQuoteNotSupported 來源不提供該商品報價
+var ErrQuoteNotSupported = errors.New("來源不支援此商品")
+
+// ErrStaleQuote 報價時間過舊或數值長時間停滯
+var ErrStaleQuote = errors.New("報價資料過期")
+
+// ErrOutlierQuote 報價超出合理範圍 (通常是 XPath 抓錯節點)
+var ErrOutlierQuote = errors.New("報價數值異常")
+
+// QuoteSource 報價來源介面
+// 新增報價來源只需實作此介面並加入來源清單，不必修改 main()
+type QuoteSource interface {
+	Name() string
+	FetchSpot(ctx context.Context) (Quote, error)   // 加權指數
+	FetchFuture(ctx context.Context) (Quote, error) // 台指期
+}
+
+// QuoteCoverage 宣告來源提供哪些商品的報價 (未實作的來源視為加權與期貨都提供)
+// 共識 (QUOTE_QUORUM) 依商品分別計算，啟動時以此檢查設定是否可能達成
+type QuoteCoverage interface {
+	QuotesSpot() bool
+	QuotesFuture() bool
+}
+
+// CountCoverage 提供加權與期貨報價的來源數量
+func CountCoverage(sources []QuoteSource) (spot, future int) {
+	for _, src := range sources {
+		c, ok := src.(QuoteCoverage)
+		if !ok || c.QuotesSpot() {
+			spot++
+		}
+		if !ok || c.QuotesFuture() {
+			future++
+		}
+	}
+	return spot, future
+}
+
+// XPathSource 透過 URL 跟 XPath 擷取網頁節點作為報價
+type XPathSource struct {
+	Label     string
+	Selectors Selectors
+	Fetcher   *Fetcher
+	Clock     Clock // 報價時間 (網頁沒有提供，以抓取時間代替)
+}
+
+// NewYahooSource 以 Yahoo 股市頁面為來源
+func NewYahooSource(sel Selectors, f *Fetcher) *XPathSource {
+	return &XPathSource{
+		Label:     "yahoo",
+		Selectors: sel,
+		Fetcher:   f,
+		Clock:     SystemClock{},
+	}
+}
+
+func (s *XPathSource) Name() string {
+	return s.Label
+}
+
+// QuotesSpot 有設定加權的頁面與 XPath 才提供 (見 fetch)
+func (s *XPathSource) QuotesSpot() bool {
+	return s.Selectors.SpotURL != "" && s.Selectors.SpotXPath != ""
+}
+
+func (s *XPathSource) QuotesFuture() bool {
+	return s.Selectors.FutureURL != "" && s.Selectors.FutureXPath != ""
+}
+
+func (s *XPathSource) FetchSpot(ctx context.Context) (Quote, error) {
+	return s.fetch(ctx, s.Selectors.SpotURL, s.Selectors.SpotXPath, s.Selectors.SpotCandidates())
+}
+
+func (s *XPathSource) FetchFuture(ctx context.Context) (Quote, error) {
+	return s.fetch(ctx, s.Selectors.FutureURL, s.Selectors.FutureXPath, s.Selectors.FutureCandidates())
+}
+
+// fetch 先以設定的 XPath 取值，找不到節點時再依序嘗試候選 XPath
+func (s *XPathSource) fetch(ctx context.Context, urlLink, xpathStr string, fallbacks []string) (Quote, error) {
+	if urlLink == "" || xpathStr == "" {
+		return Quote{}, ErrQuoteNotSupported
+	}
+
+	body, err := s.Fetcher.Get(ctx, urlLink)
+	if err != nil {
+		return Quote{}, fmt.Errorf("載入 URL 失敗: %v", err)
+	}
+
+	raw, err := FindValueString(body, xpathStr)
+	for _, expr := range fallbacks {
+		if err == nil {
+			break
+		}
+		if raw, err = FindValueString(body, expr); err == nil {
+			log.Printf("⚠️ [%s] 設定的 XPath 失效，改用候選 XPath: %s", s.Label, expr)
+		}
+	}
+	if err != nil {
+		return Quote{}, err
+	}
+
+	val, err := ParseToFloat(raw)
+	if err != nil {
+		return Quote{}, fmt.Errorf("解析失敗: %w", err)
+	}
+
+	// 網頁上沒有可靠的報價時間，以抓取時間代替
+	return Quote{Value: val, Time: s.Clock.Now(), Source: s.Label, Raw: raw}, nil
+}
+
+// sourceFactories 可用的報價來源 (名稱 -> 建構函式)
+var sourceFactories = map[string]func(cfg *Config, f *Fetcher) QuoteSource{
+	"yahoo":  func(cfg *Config, f *Fetcher) QuoteSource { return NewYahooSource(cfg.Selectors, f) },
+	"twse":   func(cfg *Config, f *Fetcher) QuoteSource { return NewTWSESource(f) },
+	"taifex": func(cfg *Config, f *Fetcher) QuoteSource { return NewTAIFEXSource(f) },
+}
+
+// NewSources 依 QUOTE_SOURCES 建立報價來源清單，順序即為嘗試的優先順序
+// 所有來源共用同一個 Fetcher (連線池)；空白項目略過，重複的來源視為設定錯誤 (會讓共識形同虛設)
+func NewSources(cfg *Config, f *Fetcher) ([]QuoteSource, error) {
+	var sources []QuoteSource
+	seen := map[string]bool{}
+	for _, name := range cfg.QuoteSources {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		factory, ok := sourceFactories[name]
+		if !ok {
+			return nil, fmt.Errorf("未知的報價來源: %s", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("重複的報價來源: %s", name)
+		}
+		seen[name] = true
+		sources = append(sources, factory(cfg, f))
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("未設定任何報價來源")
+	}
+	return sources, nil
+}
+
+// SourceLoader 每次檢查前取得報價來源
+type SourceLoader interface {
+	Sources(ctx context.Context) ([]QuoteSource, error)
+}
+
+// StaticSources 固定的報價來源清單
+type StaticSources []QuoteSource
+
+func (s StaticSources) Sources(ctx context.Context) ([]QuoteSource, error) {
+	return s, nil
+}
+
+// SelectorsOverrider 提供爬蟲設定覆寫 (沒有覆寫時回傳 nil)
+type SelectorsOverrider interface {
+	SelectorsOverride(ctx context.Context) (*Selectors, error)
+}
+
+// ConfigSources 依 QUOTE_SOURCES 建立報價來源
+// 每次都重新讀取覆寫設定: 頁面改版時不必重新部署 (或重啟常駐程式) 即可修正 XPath
+type ConfigSources struct {
+	Config   *Config
+	Fetcher  *Fetcher
+	Override SelectorsOverrider // nil 代表不覆寫
+}
+
+func (s *ConfigSources) Sources(ctx context.Context) ([]QuoteSource, error) {
+	cfg := *s.Config // 覆寫只影響本次
+	if s.Override != nil {
+		if override, err := s.Override.SelectorsOverride(ctx); err != nil {
+			log.Printf("⚠️ 讀取 Firestore 爬蟲設定失敗，使用原設定: %v", err)
+		} else if override != nil {
+			merged := cfg.Selectors
+			merged.Merge(*override)
+			if err := merged.Validate(); err != nil {
+				log.Printf("⚠️ Firestore 爬蟲設定無效，使用原設定: %v", err)
+			} else {
+				fmt.Println("套用 Firestore 爬蟲設定")
+				cfg.Selectors = merged
+			}
+		}
+	}
+	return NewSources(&cfg, s.Fetcher)
+}
+
+// ScrapePolicy 報價採用規則
+type ScrapePolicy struct {
+	Required  int           // 需幾個來源報價一致才採用 (<= 1 代表第一個成功的來源即採用)
+	Tolerance float64       // 來源間可接受的差距 (點)
+	MaxAge    time.Duration // 報價時間最多可落後多久 (0 代表不檢查)
+	Bounds    PriceBounds   // 報價合理範圍
+
+	SpotRef   float64 // 加權前值 (0 代表沒有前值，不檢查偏離)
+	FutureRef float64 // 期貨前值 (0 代表沒有前值，不檢查偏離)
+
+	MaxSkew time.Duration // 加權與期貨取得時間最多可相差多久 (0 代表不檢查)
+
+	Clock Clock // 判斷報價是否過期的目前時間 (nil 代表系統時間)
+}
+
+func (p ScrapePolicy) now() time.Time {
+	if p.Clock == nil {
+		return time.Now()
+	}
+	return p.Clock.Now()
+}
+
+// PriceBounds 報價合理範圍
+type PriceBounds struct {
+	Min          float64 // 數量級下限 (0 代表不檢查)
+	Max          float64 // 數量級上限 (0 代表不檢查)
+	MaxDeviation float64 // 與前值的最大偏離比例 (0.1 = 10%, 0 代表不檢查)
+}
+
+// Check 檢查報價是否在合理範圍內
+// 超出範圍代表抓到了錯誤的節點 (成交量、漲跌幅、0...)，應視為爬蟲錯誤而非行情
+func (b PriceBounds) Check(q Quote, ref float64) error {
+	if (b.Min > 0 && q.Value < b.Min) || (b.Max > 0 && q.Value > b.Max) {
+		return fmt.Errorf("%w: 原始字串 %q 解析為 %.2f，不在合理範圍 %.0f ~ %.0f",
+			ErrOutlierQuote, q.Raw, q.Value, b.Min, b.Max)
+	}
+	if b.MaxDeviation > 0 && ref > 0 && math.Abs(q.Value-ref)/ref > b.MaxDeviation {
+		return fmt.Errorf("%w: 原始字串 %q 解析為 %.2f，偏離前值 %.2f 超過 %.0f%%",
+			ErrOutlierQuote, q.Raw, q.Value, ref, b.MaxDeviation*100)
+	}
+	return nil
+}
+
+// ScrapeResult 爬蟲結果
+type ScrapeResult struct {
+	Spot   Quote
+	Future Quote
+	Notes  []string // 來源不一致等提示訊息
+}
+
+// Skew 加權與期貨取得時間的差距
+func (r ScrapeResult) Skew() time.Duration {
+	skew := r.Spot.FetchedAt.Sub(r.Future.FetchedAt)
+	if skew < 0 {
+		skew = -skew
+	}
+	return skew
+}
+
+// checkAge 報價時間超過 MaxAge 視為過期
+func (p ScrapePolicy) checkAge(q Quote) error {
+	if p.MaxAge <= 0 || q.Time.IsZero() {
+		return nil
+	}
+	if age := p.now().Sub(q.Time); age > p.MaxAge {
+		return fmt.Errorf("%w: 報價時間 %s 已落後 %s (上限 %s)",
+			ErrStaleQuote, q.Time.In(loc).Format("01-02 15:04:05"), age.Truncate(time.Second), p.MaxAge)
+	}
+	return nil
+}
+
+// selectQuote 依序向來源取得報價，並依 ScrapePolicy 決定採用的報價
+// ctx 取消後不再嘗試剩餘的來源
+// 回傳: 採用的報價, 提示訊息 (來源不一致), 錯誤
+func selectQuote(ctx context.Context, sources []QuoteSource, policy ScrapePolicy, ref float64, fetch func(QuoteSource, context.Context) (Quote, error)) (Quote, string, error) {
+	var errs error
+	var got []Quote
+	for _, src := range sources {
+		if ctx.Err() != nil {
+			errs = errors.Join(errs, ctx.Err())
+			break
+		}
+		q, err := fetch(src, ctx)
+		q.FetchedAt = policy.now()
+		if err == nil {
+			err = policy.checkAge(q)
+		}
+		if err == nil {
+			if err = policy.Bounds.Check(q, ref); err != nil {
+				log.Printf("⚠️ [%s] 報價遭拒絕: %v", src.Name(), err)
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, ErrQuoteNotSupported) {
+				errs = errors.Join(errs, fmt.Errorf("[%s] %w", src.Name(), err))
+			}
+			continue
+		}
+
+		if policy.Required <= 1 {
+			return q, "", nil
+		}
+
+		// 與先前成功的來源比對，找出在容許誤差內的報價
+		agreed := 1
+		for _, prev := range got {
+			if math.Abs(prev.Value-q.Value) <= policy.Tolerance {
+				agreed++
+			}
+		}
+		got = append(got, q)
+		if agreed < policy.Required {
+			continue
+		}
+
+		// 達成共識: 採用優先順序最高且與本次報價一致的來源
+		var note string
+		for _, prev := range got {
+			if math.Abs(prev.Value-q.Value) <= policy.Tolerance {
+				q = prev
+				break
+			}
+		}
+		for _, prev := range got {
+			if math.Abs(prev.Value-q.Value) > policy.Tolerance {
+				note = joinNote(note, fmt.Sprintf("%s 報價 %.2f 與 %s 報價 %.2f 不一致", prev.Source, prev.Value, q.Source, q.Value))
+			}
+		}
+		return q, note, nil
+	}
+
+	if len(got) > 0 {
+		var vals []string
+		for _, q := range got {
+			vals = append(vals, fmt.Sprintf("%s=%.2f", q.Source, q.Value))
+		}
+		errs = errors.Join(errs, fmt.Errorf("報價來源未達共識 (需 %d 個來源差距在 %.2f 點內): %s",
+			policy.Required, policy.Tolerance, strings.Join(vals, ", ")))
+	}
+	if errs == nil {
+		errs = fmt.Errorf("沒有可用的報價來源")
+	}
+	return Quote{}, "", errs
+}
+
+func joinNote(note, s string) string {
+	if note == "" {
+		return s
+	}
+	return note + "; " + s
+}
+
+// ScrapeFuture 只抓取台指期 (盤前/夜盤期貨為 0 時重試用，不重新抓取加權)
+// 回傳: 期貨報價, 提示訊息, 錯誤
+func ScrapeFuture(ctx context.Context, sources []QuoteSource, policy ScrapePolicy) (Quote, []string, error) {
+	q, note, err := selectQuote(ctx, sources, policy, policy.FutureRef, QuoteSource.FetchFuture)
+	if err != nil {
+		return Quote{}, nil, fmt.Errorf("抓取台指期失敗: %w", err)
+	}
+	if note == "" {
+		return q, nil, nil
+	}
+	return q, []string{"台指期" + note}, nil
+}
+
+// ScrapeData 同時抓取台指期與加權指數，兩者共用 ctx 的期限
+// 期限到了仍未完成的商品會回傳 ctx 的錯誤
+func ScrapeData(ctx context.Context, sources []QuoteSource, policy ScrapePolicy) (res ScrapeResult, errs error) {
+	type result struct {
+		quote Quote
+		note  string
+		err   error
+	}
+
+	run := func(ref float64, fetch func(QuoteSource, context.Context) (Quote, error)) <-chan result {
+		ch := make(chan result, 1)
+		go func() {
+			q, note, err := selectQuote(ctx, sources, policy, ref, fetch)
+			ch <- result{q, note, err}
+		}()
+		return ch
+	}
+	wait := func(ch <-chan result) result {
+		select {
+		case r := <-ch:
+			return r
+		case <-ctx.Done():
+			return result{err: ctx.Err()}
+		}
+	}
+
+	futureCh := run(policy.FutureRef, QuoteSource.FetchFuture)
+	spotCh := run(policy.SpotRef, QuoteSource.FetchSpot)
+
+	// 取得台指期
+	if r := wait(futureCh); r.err != nil {
+		errs = errors.Join(errs, fmt.Errorf("抓取台指期失敗: %w", r.err))
+	} else {
+		res.Future = r.quote
+		if r.note != "" {
+			res.Notes = append(res.Notes, "台指期"+r.note)
+		}
+	}
+
+	// 取得加權指數
+	if r := wait(spotCh); r.err != nil {
+		errs = errors.Join(errs, fmt.Errorf("抓取加權指數失敗: %w", r.err))
+	} else {
+		res.Spot = r.quote
+		if r.note != "" {
+			res.Notes = append(res.Notes, "加權"+r.note)
+		}
+	}
+
+	// 兩者取得時間差距過大時，價差可能來自不同時間點的快照
+	if errs == nil && policy.MaxSkew > 0 && res.Skew() > policy.MaxSkew {
+		res.Notes = append(res.Notes, fmt.Sprintf("加權與期貨取得時間相差 %s (上限 %s)，價差可能失真",
+			res.Skew().Truncate(time.Millisecond), policy.MaxSkew))
+	}
+
+	return
+}
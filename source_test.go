@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSource 測試用報價來源
+type fakeSource struct {
+	name      string
+	spot      float64
+	future    float64
+	spotErr   error
+	futureErr error
+	quoteTime time.Time // 報價時間 (零值代表當下)
+
+	spotDelay   time.Duration // 模擬加權回應時間
+	futureDelay time.Duration // 模擬期貨回應時間
+}
+
+func (f *fakeSource) quote(val float64) Quote {
+	t := f.quoteTime
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return Quote{Value: val, Time: t, Source: f.name, Raw: fmt.Sprintf("%.2f", val)}
+}
+
+func (f *fakeSource) Name() string { return f.name }
+
+func (f *fakeSource) FetchSpot(ctx context.Context) (Quote, error) {
+	if err := Sleep(ctx, f.spotDelay); err != nil {
+		return Quote{}, err
+	}
+	if f.spotErr != nil {
+		return Quote{}, f.spotErr
+	}
+	return f.quote(f.spot), nil
+}
+
+func (f *fakeSource) FetchFuture(ctx context.Context) (Quote, error) {
+	if err := Sleep(ctx, f.futureDelay); err != nil {
+		return Quote{}, err
+	}
+	if f.futureErr != nil {
+		return Quote{}, f.futureErr
+	}
+	return f.quote(f.future), nil
+}
+
+func TestScrapeData(t *testing.T) {
+	errDown := errors.New("連線逾時")
+
+	tests := []struct {
+		name       string
+		sources    []QuoteSource
+		policy     ScrapePolicy
+		wantSpot   float64
+		wantFuture float64
+		wantErr    string // 預期錯誤訊息包含的關鍵字, 空字串代表無錯誤
+		wantNote   string // 預期提示訊息包含的關鍵字
+		wantSource string // 預期採用的期貨來源
+	}{
+		{
+			name:       "單一來源_成功",
+			sources:    []QuoteSource{&fakeSource{name: "a", spot: 20000, future: 20010}},
+			wantSpot:   20000,
+			wantFuture: 20010,
+		},
+		{
+			name: "第一來源失敗_改用第二來源",
+			sources: []QuoteSource{
+				&fakeSource{name: "a", spotErr: errDown, futureErr: errDown},
+				&fakeSource{name: "b", spot: 20000, future: 20010},
+			},
+			wantSpot:   20000,
+			wantFuture: 20010,
+		},
+		{
+			name: "來源不支援商品_略過",
+			sources: []QuoteSource{
+				&fakeSource{name: "spot-only", spot: 20000, futureErr: ErrQuoteNotSupported},
+				&fakeSource{name: "b", spot: 19000, future: 20010},
+			},
+			wantSpot:   20000,
+			wantFuture: 20010,
+		},
+		{
+			name: "期貨全部失敗_回傳錯誤但保留現貨",
+			sources: []QuoteSource{
+				&fakeSource{name: "a", spot: 20000, futureErr: errDown},
+			},
+			wantSpot: 20000,
+			wantErr:  "抓取台指期失敗",
+		},
+		{
+			name:    "沒有來源",
+			wantErr: "沒有可用的報價來源",
+		},
+
+		// --- 報價時間測試 ---
+		{
+			name:   "第一來源報價過期_改用第二來源",
+			policy: ScrapePolicy{MaxAge: 10 * time.Minute},
+			sources: []QuoteSource{
+				&fakeSource{name: "a", spot: 19000, future: 19010, quoteTime: time.Now().Add(-time.Hour)},
+				&fakeSource{name: "b", spot: 20000, future: 20010},
+			},
+			wantSpot:   20000,
+			wantFuture: 20010,
+			wantSource: "b",
+		},
+		{
+			name:   "所有來源報價過期_回傳過期錯誤",
+			policy: ScrapePolicy{MaxAge: 10 * time.Minute},
+			sources: []QuoteSource{
+				&fakeSource{name: "a", spot: 19000, future: 19010, quoteTime: time.Now().Add(-time.Hour)},
+			},
+			wantErr: "報價資料過期",
+		},
+
+		// --- 合理範圍測試 ---
+		{
+			name: "第一來源抓到成交量_改用第二來源",
+			policy: ScrapePolicy{
+				Bounds:  PriceBounds{Min: 1000, Max: 100000, MaxDeviation: 0.1},
+				SpotRef: 20000, FutureRef: 20000,
+			},
+			sources: []QuoteSource{
+				&fakeSource{name: "a", spot: 186522, future: 20010},
+				&fakeSource{name: "b", spot: 20000, future: 20010},
+			},
+			wantSpot:   20000,
+			wantFuture: 20010,
+		},
+		{
+			name: "偏離前值超過10%_回傳異常錯誤並附上原始字串",
+			policy: ScrapePolicy{
+				Bounds:  PriceBounds{Min: 1000, Max: 100000, MaxDeviation: 0.1},
+				SpotRef: 20000, FutureRef: 20000,
+			},
+			sources: []QuoteSource{
+				&fakeSource{name: "a", spot: 20000, future: 2001},
+			},
+			wantSpot: 20000,
+			wantErr:  `原始字串 "2001.00" 解析為 2001.00，偏離前值 20000.00 超過 10%`,
+		},
+
+		// --- 共識測試 ---
+		{
+			name:   "共識_兩來源一致_採用優先來源",
+			policy: ScrapePolicy{Required: 2, Tolerance: 5},
+			sources: []QuoteSource{
+				&fakeSource{name: "a", spot: 20000, future: 20010},
+				&fakeSource{name: "b", spot: 20001, future: 20012},
+			},
+			wantSpot:   20000,
+			wantFuture: 20010,
+			wantSource: "a",
+		},
+		{
+			name:   "共識_第一來源偏離_採用後兩個一致的來源並提示",
+			policy: ScrapePolicy{Required: 2, Tolerance: 5},
+			sources: []QuoteSource{
+				&fakeSource{name: "a", spot: 20000, future: 2001},
+				&fakeSource{name: "b", spot: 20001, future: 20010},
+				&fakeSource{name: "c", spot: 20002, future: 20011},
+			},
+			wantSpot:   20000,
+			wantFuture: 20010,
+			wantNote:   "a 報價 2001.00 與 b 報價 20010.00 不一致",
+			wantSource: "b",
+		},
+		{
+			name:   "共識_來源不一致_回傳錯誤",
+			policy: ScrapePolicy{Required: 2, Tolerance: 5},
+			sources: []QuoteSource{
+				&fakeSource{name: "a", spot: 20000, future: 20010},
+				&fakeSource{name: "b", spot: 20001, future: 20100},
+			},
+			wantSpot: 20000,
+			wantErr:  "報價來源未達共識",
+		},
+		{
+			name:   "共識_只有一個來源成功_回傳錯誤",
+			policy: ScrapePolicy{Required: 2, Tolerance: 5},
+			sources: []QuoteSource{
+				&fakeSource{name: "a", spot: 20000, future: 20010},
+				&fakeSource{name: "b", spot: 20001, futureErr: errDown},
+			},
+			wantSpot: 20000,
+			wantErr:  "[b] 連線逾時",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ScrapeData(context.Background(), tt.sources, tt.policy)
+			spotVal, futureVal := res.Spot.Value, res.Future.Value
+
+			if spotVal != tt.wantSpot || futureVal != tt.wantFuture {
+				t.Errorf("ScrapeData() = (%.2f, %.2f), want (%.2f, %.2f)", spotVal, futureVal, tt.wantSpot, tt.wantFuture)
+			}
+
+			if note := strings.Join(res.Notes, "\n"); !strings.Contains(note, tt.wantNote) {
+				t.Errorf("ScrapeData() notes = %v, want substring %v", note, tt.wantNote)
+			}
+			if tt.wantSource != "" && res.Future.Source != tt.wantSource {
+				t.Errorf("ScrapeData() future source = %v, want %v", res.Future.Source, tt.wantSource)
+			}
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ScrapeData() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ScrapeData() error = %v, want substring %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewSources(t *testing.T) {
+	sel := Selectors{SpotURL: "https://example.com/spot", SpotXPath: "//span", FutureURL: "https://example.com/future", FutureXPath: "//span"}
+
+	tests := []struct {
+		sources    []string
+		selectors  Selectors
+		want       int
+		wantSpot   int // 提供加權的來源數
+		wantFuture int // 提供期貨的來源數
+		wantErr    bool
+	}{
+		{[]string{"twse", "taifex", "yahoo"}, sel, 3, 2, 2, false},
+		{[]string{"twse", "taifex"}, sel, 2, 1, 1, false},
+		{[]string{"twse", "yahoo"}, Selectors{SpotURL: "https://example.com/spot", SpotXPath: "//span"}, 2, 2, 0, false},
+		{[]string{"twse", "", " "}, sel, 1, 1, 0, false},
+		{[]string{"yahoo", " Yahoo"}, sel, 0, 0, 0, true},
+		{[]string{"bloomberg"}, sel, 0, 0, 0, true},
+		{[]string{""}, sel, 0, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := NewSources(&Config{QuoteSources: tt.sources, Selectors: tt.selectors}, nil)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewSources(%q) error = %v, wantErr %v", tt.sources, err, tt.wantErr)
+		}
+		if len(got) != tt.want {
+			t.Errorf("NewSources(%q) = %d sources, want %d", tt.sources, len(got), tt.want)
+		}
+		if spot, future := CountCoverage(got); spot != tt.wantSpot || future != tt.wantFuture {
+			t.Errorf("CountCoverage(%q) = (%d, %d), want (%d, %d)", tt.sources, spot, future, tt.wantSpot, tt.wantFuture)
+		}
+	}
+}
+
+func TestPriceBounds_Check(t *testing.T) {
+	b := PriceBounds{Min: 1000, Max: 100000, MaxDeviation: 0.1}
+
+	tests := []struct {
+		name    string
+		val     float64
+		ref     float64
+		wantErr bool
+	}{
+		{"正常", 20100, 20000, false},
+		{"沒有前值_只檢查數量級", 20100, 0, false},
+		{"抓到0", 0, 20000, true},
+		{"抓到漲跌幅", 1.25, 20000, true},
+		{"抓到成交量", 186522, 20000, true},
+		{"漲停邊緣", 21990, 20000, false},
+		{"超過漲停", 22100, 20000, true},
+		{"超過跌停", 17900, 20000, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := b.Check(Quote{Value: tt.val, Raw: fmt.Sprint(tt.val)}, tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check(%.2f, %.2f) error = %v, wantErr %v", tt.val, tt.ref, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrOutlierQuote) {
+				t.Errorf("Check() error = %v, want ErrOutlierQuote", err)
+			}
+		})
+	}
+}
+
+// futureOnlySource 不應被抓取加權的來源
+type futureOnlySource struct {
+	*fakeSource
+	t *testing.T
+}
+
+func (f futureOnlySource) FetchSpot(ctx context.Context) (Quote, error) {
+	f.t.Errorf("[%s] FetchSpot() called, want futures only", f.name)
+	return Quote{}, ErrQuoteNotSupported
+}
+
+func TestScrapeFuture(t *testing.T) {
+	sources := []QuoteSource{
+		futureOnlySource{&fakeSource{name: "c", future: 27100}, t},
+		futureOnlySource{&fakeSource{name: "a", future: 27000}, t},
+		futureOnlySource{&fakeSource{name: "b", future: 27002}, t},
+	}
+
+	q, notes, err := ScrapeFuture(context.Background(), sources, ScrapePolicy{Required: 2, Tolerance: 5})
+	if err != nil {
+		t.Fatalf("ScrapeFuture() unexpected error: %v", err)
+	}
+	if q.Value != 27000 || q.Source != "a" {
+		t.Errorf("ScrapeFuture() = %.2f (%s), want 27000.00 (a)", q.Value, q.Source)
+	}
+	if len(notes) != 1 || !strings.HasPrefix(notes[0], "台指期c 報價 27100.00") {
+		t.Errorf("ScrapeFuture() notes = %q, want disagreement of c", notes)
+	}
+
+	if _, _, err := ScrapeFuture(context.Background(), []QuoteSource{&fakeSource{name: "x", futureErr: errors.New("連線逾時")}}, ScrapePolicy{}); err == nil || !strings.Contains(err.Error(), "抓取台指期失敗") {
+		t.Errorf("ScrapeFuture() error = %v, want futures error", err)
+	}
+}
+
+func TestScrapeData_Concurrent(t *testing.T) {
+	delay := 100 * time.Millisecond
+
+	t.Run("加權與期貨同時抓取", func(t *testing.T) {
+		src := &fakeSource{name: "slow", spot: 20000, future: 20010, spotDelay: delay, futureDelay: delay}
+
+		start := time.Now()
+		res, err := ScrapeData(context.Background(), []QuoteSource{src}, ScrapePolicy{MaxSkew: time.Second})
+		if err != nil {
+			t.Fatalf("ScrapeData() unexpected error: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed >= 2*delay {
+			t.Errorf("ScrapeData() took %v, want concurrent fetch (< %v)", elapsed, 2*delay)
+		}
+		if res.Skew() > delay/2 {
+			t.Errorf("ScrapeData() skew = %v, want fetch times close together", res.Skew())
+		}
+		if len(res.Notes) != 0 {
+			t.Errorf("ScrapeData() notes = %v, want none", res.Notes)
+		}
+	})
+
+	t.Run("取得時間差距過大_提示價差可能失真", func(t *testing.T) {
+		src := &fakeSource{name: "skewed", spot: 20000, future: 20010, spotDelay: delay}
+
+		res, err := ScrapeData(context.Background(), []QuoteSource{src}, ScrapePolicy{MaxSkew: delay / 4})
+		if err != nil {
+			t.Fatalf("ScrapeData() unexpected error: %v", err)
+		}
+		if note := strings.Join(res.Notes, "\n"); !strings.Contains(note, "價差可能失真") {
+			t.Errorf("ScrapeData() notes = %v, want skew warning", note)
+		}
+	})
+
+	t.Run("共用期限_逾時回傳錯誤並保留已完成的報價", func(t *testing.T) {
+		src := &fakeSource{name: "hang", spot: 20000, future: 20010, spotDelay: time.Second}
+
+		ctx, cancel := context.WithTimeout(context.Background(), delay)
+		defer cancel()
+
+		start := time.Now()
+		res, err := ScrapeData(ctx, []QuoteSource{src}, ScrapePolicy{})
+		if elapsed := time.Since(start); elapsed >= time.Second {
+			t.Errorf("ScrapeData() took %v, want return at deadline", elapsed)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "抓取加權指數失敗") {
+			t.Errorf("ScrapeData() error = %v, want spot deadline exceeded", err)
+		}
+		if res.Future.Value != 20010 {
+			t.Errorf("ScrapeData() future = %.2f, want 20010", res.Future.Value)
+		}
+	})
+}
+
+func TestXPathSource_QuoteTime(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><span class="price">27,050.12</span></body></html>`))
+	}))
+	defer srv.Close()
+
+	// 網頁沒有報價時間，以注入的時鐘作為報價時間 (MaxAge/MaxSkew 與模擬時間比較)
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, loc)
+	src := NewYahooSource(Selectors{SpotURL: srv.URL, SpotXPath: `//span[@class="price"]`}, newTestFetcher())
+	src.Clock = NewFakeClock(now)
+
+	q, err := src.FetchSpot(context.Background())
+	if err != nil {
+		t.Fatalf("FetchSpot() unexpected error: %v", err)
+	}
+	if q.Value != 27050.12 || !q.Time.Equal(now) {
+		t.Errorf("FetchSpot() = (%.2f, %v), want (27050.12, %v)", q.Value, q.Time, now)
+	}
+
+	policy := ScrapePolicy{MaxAge: time.Minute, Clock: src.Clock}
+	if err := policy.checkAge(q); err != nil {
+		t.Errorf("checkAge() unexpected error: %v", err)
+	}
+}
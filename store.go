@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrStateConflict 讀取後狀態已被其他執行更新 (樂觀並行控制)
+var ErrStateConflict = errors.New("狀態已被其他執行更新")
+
+// StateStore 狀態儲存 (上次通知的價差、高低點、錯誤計數...)
+// Load 會記錄讀取時的版本，Save 時若版本已改變則回傳 ErrStateConflict 且不寫入
+type StateStore interface {
+	Load(ctx context.Context) (*Data, error) // 尚未儲存過時回傳空的 Data
+	Save(ctx context.Context, d *Data) error
+	Close() error
+}
+
+// stateStores 可用的狀態儲存 (STATE_BACKEND -> 建構函式)
+var stateStores = map[string]func(cfg *Config) StateStore{
+	"firestore": func(cfg *Config) StateStore { return NewFirestoreStore(cfg.GCPProject) },
+	"file":      func(cfg *Config) StateStore { return NewFileStore(cfg.StateFile) },
+	"memory":    func(cfg *Config) StateStore { return NewMemoryStore() },
+}
+
+// NewStateStore 依 STATE_BACKEND 建立狀態儲存
+func NewStateStore(cfg *Config) (StateStore, error) {
+	factory, ok := stateStores[cfg.StateBackend]
+	if !ok {
+		return nil, fmt.Errorf("未知的狀態儲存: %s", cfg.StateBackend)
+	}
+	return factory(cfg), nil
+}
+
+// FileStore 以本機 JSON 檔保存狀態 (自行架設時不需要 GCP 憑證)
+// 版本檢查只在同一個程序內是原子的
+type FileStore struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// fileState 狀態檔格式
+type fileState struct {
+	Revision int64 `json:"revision"` // 每次儲存加一
+	Data     *Data `json:"data"`
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+func (s *FileStore) Load(ctx context.Context) (*Data, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	st.Data.revision = st.Revision
+	return st.Data, nil
+}
+
+// read 讀取狀態檔，檔案不存在時回傳空的狀態 (版本 0)
+func (s *FileStore) read() (*fileState, error) {
+	st := &fileState{Data: &Data{}}
+	body, err := os.ReadFile(s.Path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// 第一次運行，檔案不存在
+			return st, nil
+		}
+		return nil, fmt.Errorf("讀取狀態檔失敗: %w", err)
+	}
+	if err := json.Unmarshal(body, st); err != nil {
+		return nil, fmt.Errorf("解析狀態檔失敗: %w", err)
+	}
+	return st, nil
+}
+
+// Save 先寫入暫存檔再改名，避免寫到一半中斷時留下損壞的狀態檔
+func (s *FileStore) Save(ctx context.Context, d *Data) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.read()
+	if err != nil {
+		return err
+	}
+	if current.Revision != d.revision {
+		return ErrStateConflict
+	}
+
+	st := fileState{Revision: current.Revision + 1, Data: d}
+	body, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("建立狀態檔目錄失敗: %w", err)
+		}
+	}
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, body, 0o644); err != nil {
+		return fmt.Errorf("寫入狀態檔失敗: %w", err)
+	}
+	if err := os.Rename(tmp, s.Path); err != nil {
+		return fmt.Errorf("寫入狀態檔失敗: %w", err)
+	}
+
+	d.revision = st.Revision
+	return nil
+}
+
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// MemoryStore 記憶體中的狀態 (測試用，程式結束即遺失)
+type MemoryStore struct {
+	mu   sync.Mutex
+	data *Data
+	rev  int64 // 每次儲存加一
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Load 回傳副本，呼叫端修改不會影響已儲存的狀態
+func (s *MemoryStore) Load(ctx context.Context) (*Data, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := &Data{}
+	if s.data != nil {
+		*d = *s.data
+	}
+	d.revision = s.rev
+	return d, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, d *Data) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d.revision != s.rev {
+		return ErrStateConflict
+	}
+	s.rev++
+	d.revision = s.rev
+	saved := *d
+	s.data = &saved
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
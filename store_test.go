@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateStore_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	stores := []struct {
+		name  string
+		store StateStore
+	}{
+		{"file", NewFileStore(filepath.Join(dir, "state", "watchtwii.json"))},
+		{"memory", NewMemoryStore()},
+	}
+
+	for _, tt := range stores {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			// 尚未儲存過: 回傳空的 Data
+			d, err := tt.store.Load(ctx)
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			if d.LastTWIIValue != 0 || d.ErrorCount != 0 {
+				t.Errorf("Load() = %+v, want empty data", d)
+			}
+
+			want := &Data{
+				LastTWIIValue:  27000.5,
+				LastDiffValue:  -35.25,
+				SpotHigh:       27100,
+				FutureContract: "TXFK6",
+				SpotQuoteTime:  time.Date(2026, 10, 16, 9, 30, 0, 0, loc),
+				ErrorCount:     2,
+				LastError:      "連線逾時",
+			}
+			if err := tt.store.Save(ctx, want); err != nil {
+				t.Fatalf("Save() unexpected error: %v", err)
+			}
+
+			// 修改呼叫端的 Data 不影響已儲存的狀態
+			want.ErrorCount = 99
+
+			got, err := tt.store.Load(ctx)
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			if got.LastTWIIValue != 27000.5 || got.LastDiffValue != -35.25 || got.SpotHigh != 27100 ||
+				got.FutureContract != "TXFK6" || got.ErrorCount != 2 || got.LastError != "連線逾時" {
+				t.Errorf("Load() = %+v, want saved data", got)
+			}
+			if !got.SpotQuoteTime.Equal(want.SpotQuoteTime) {
+				t.Errorf("Load() quote time = %v, want %v", got.SpotQuoteTime, want.SpotQuoteTime)
+			}
+
+			// 以舊版本儲存 (讀取後已被其他執行寫入) 應回傳衝突且不覆蓋
+			stale, _ := tt.store.Load(ctx)
+			if err := tt.store.Save(ctx, got); err != nil {
+				t.Fatalf("Save() unexpected error: %v", err)
+			}
+			stale.ErrorCount = 5
+			if err := tt.store.Save(ctx, stale); !errors.Is(err, ErrStateConflict) {
+				t.Errorf("Save() with stale revision error = %v, want ErrStateConflict", err)
+			}
+			if latest, _ := tt.store.Load(ctx); latest.ErrorCount != 2 {
+				t.Errorf("Load() after conflict ErrorCount = %d, want 2", latest.ErrorCount)
+			}
+		})
+	}
+}
+
+func TestFileStore_Corrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileStore(path).Load(context.Background()); err == nil {
+		t.Errorf("Load() want error for corrupted file")
+	}
+}
+
+func TestNewStateStore(t *testing.T) {
+	tests := []struct {
+		backend string
+		wantErr bool
+	}{
+		{"firestore", false},
+		{"file", false},
+		{"memory", false},
+		{"bolt", true},
+	}
+
+	for _, tt := range tests {
+		_, err := NewStateStore(&Config{StateBackend: tt.backend, StateFile: "state.json"})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewStateStore(%q) error = %v, wantErr %v", tt.backend, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// 收盤摘要: 早盤 (13:45) 與夜盤 (05:00) 收盤後的第一次執行，彙整該盤的行情並通知一次
+// 行情取自歷史紀錄 (未啟用時以狀態中的高低點與收盤替代)，通知與異常次數取自狀態
+
+// sessionKey 盤別的識別 (例如 "2026-10-15 Night")，夜盤以開盤日計
+func sessionKey(session string, t time.Time) string {
+	return tradingDate(t, loc).Format(time.DateOnly) + " " + session
+}
+
+// lastClosedSession 非交易時段的 t 之前最近收盤的盤別與其開盤時間
+// 13:46 ~ 14:59 為當天早盤；05:01 ~ 08:44 為前一天開盤的夜盤
+func lastClosedSession(t time.Time) (string, time.Time, bool) {
+	t = t.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	switch current := t.Hour()*100 + t.Minute(); {
+	case current > 1345 && current < 1500:
+		return SessionMorning, day.Add(8*time.Hour + 45*time.Minute), true
+	case current > 500 && current < 845:
+		return SessionNight, day.AddDate(0, 0, -1).Add(15 * time.Hour), true
+	}
+	return SessionClosed, time.Time{}, false
+}
+
+// sessionEnd 盤別的收盤時間 (含收盤當分鐘)
+func sessionEnd(session string, open time.Time) time.Time {
+	if session == SessionNight {
+		return open.Add(14*time.Hour + time.Minute) // 隔天 05:00
+	}
+	return open.Add(5*time.Hour + time.Minute) // 13:45
+}
+
+// addSessionStat 累計本盤的通知與異常次數 (換盤時重新計算)
+func (d *Data) addSessionStat(session string, now time.Time, alerted bool, err error) {
+	if key := sessionKey(session, now); d.StatsSession != key {
+		d.StatsSession, d.StatsAlerts, d.StatsErrors, d.StatsLastError = key, 0, 0, ""
+	}
+	if alerted {
+		d.StatsAlerts++
+	}
+	if err != nil {
+		d.StatsErrors++
+		d.StatsLastError = err.Error()
+	}
+}
+
+// OHLC 開高低收 (0 代表沒有資料)
+type OHLC struct {
+	Open, High, Low, Close float64
+}
+
+func (o *OHLC) add(v float64) {
+	if o.Open == 0 {
+		o.Open = v
+	}
+	updateHighLow(v, &o.High, &o.Low)
+	o.Close = v
+}
+
+// SessionSummary 單一盤別的收盤摘要
+type SessionSummary struct {
+	Session string
+	Day     string // 開盤日
+	Ticks   int    // 歷史紀錄筆數 (0 代表以狀態替代)
+	Spot    OHLC   // 夜盤沒有加權
+	Future  OHLC
+
+	CloseDiff        float64 // 收盤價差 (加權 - 期貨)
+	MaxDiff, MinDiff float64 // 本盤最大/最小價差 (需要歷史紀錄)
+
+	Alerts    int    // 發送的行情通知數
+	Errors    int    // 抓取失敗次數
+	LastError string // 最後一次錯誤
+}
+
+// NewSessionSummary 以歷史紀錄 (該盤的報價) 與狀態彙整收盤摘要
+// 沒有歷史紀錄時以狀態中的高低點與收盤替代；兩者都沒有該盤的資料時回傳 false
+func NewSessionSummary(session string, open time.Time, ticks []Tick, d *Data) (*SessionSummary, bool) {
+	day := open.Format(time.DateOnly)
+	s := &SessionSummary{Session: session, Day: day, Ticks: len(ticks)}
+
+	for _, t := range ticks {
+		if t.Future <= 0 {
+			continue
+		}
+		first := s.Future.Close == 0
+		s.Future.add(t.Future)
+		// 盤前的加權為前次收盤，不列入本盤的開高低收
+		if session == SessionMorning && t.Spot > 0 && t.SpotSource != spotFallbackSource {
+			s.Spot.add(t.Spot)
+		}
+		if first || t.Diff > s.MaxDiff {
+			s.MaxDiff = t.Diff
+		}
+		if first || t.Diff < s.MinDiff {
+			s.MinDiff = t.Diff
+		}
+		s.CloseDiff = t.Diff
+	}
+
+	if s.Future.Close == 0 {
+		s.Ticks = 0
+		switch {
+		case session == SessionMorning && d.TradingDay == day && d.FutureHigh > 0:
+			s.Future = OHLC{High: d.FutureHigh, Low: d.FutureLow, Close: d.LastFutureValue}
+			s.Spot = OHLC{High: d.SpotHigh, Low: d.SpotLow, Close: d.LastTWIIValue}
+		case session == SessionNight && d.NightTradingDay == day && d.NightFutureHigh > 0:
+			s.Future = OHLC{High: d.NightFutureHigh, Low: d.NightFutureLow, Close: d.LastFutureValue}
+		default:
+			return nil, false
+		}
+		s.CloseDiff = d.LastDiffValue
+	}
+
+	if d.StatsSession == sessionKey(session, open) {
+		s.Alerts, s.Errors, s.LastError = d.StatsAlerts, d.StatsErrors, d.StatsLastError
+	}
+	return s, true
+}
+
+// formatPrice 價格 (0 代表沒有資料)
+func formatPrice(v float64) string {
+	if v == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+func (o OHLC) String() string {
+	return fmt.Sprintf("開 %s 高 %s 低 %s 收 %s", formatPrice(o.Open), formatPrice(o.High), formatPrice(o.Low), formatPrice(o.Close))
+}
+
+// Message 收盤摘要的通知內容
+func (s *SessionSummary) Message() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📋 [%s收盤摘要] %s\n", SessionName(s.Session), s.Day)
+	if s.Session == SessionMorning {
+		fmt.Fprintf(&b, "加權: %s\n", s.Spot)
+	}
+	fmt.Fprintf(&b, "期貨: %s\n", s.Future)
+
+	basis := "收盤價差"
+	if s.Session == SessionNight {
+		basis = "收盤價差 (以加權收盤計)"
+	}
+	if s.Ticks > 0 {
+		fmt.Fprintf(&b, "%s: %.2f (區間: %.2f ~ %.2f)\n", basis, s.CloseDiff, s.MinDiff, s.MaxDiff)
+	} else {
+		fmt.Fprintf(&b, "%s: %.2f (無歷史紀錄，開盤與價差區間從缺)\n", basis, s.CloseDiff)
+	}
+
+	fmt.Fprintf(&b, "行情通知: %d 次\n", s.Alerts)
+	if s.Errors == 0 {
+		b.WriteString("系統異常: 無")
+	} else {
+		fmt.Fprintf(&b, "系統異常: %d 次\n最後錯誤: %s", s.Errors, s.LastError)
+	}
+	return b.String()
+}
+
+// summarize 收盤後發送該盤的摘要 (每個盤別一次)
+func (r *Runner) summarize(ctx context.Context) error {
+	session, open, ok := lastClosedSession(r.Clock.Now())
+	if !ok {
+		return nil
+	}
+	key := sessionKey(session, open)
+
+	var ticks []Tick
+	loadTicks := func() {
+		if r.History == nil {
+			return
+		}
+		var err error
+		if ticks, err = r.History.Query(ctx, open, sessionEnd(session, open)); err != nil {
+			log.Printf("⚠️ 讀取歷史紀錄失敗，收盤摘要改以狀態彙整: %v", err)
+			ticks = nil
+		}
+	}
+
+	return r.notifyOnce(ctx, "收盤摘要",
+		func(d *Data) bool { return d.LastSummary == key },
+		func(d *Data) { d.LastSummary = key },
+		loadTicks,
+		func(d *Data) (string, bool) {
+			summary, ok := NewSessionSummary(session, open, ticks, d)
+			if !ok {
+				fmt.Fprintf(r.out(), "%s (%s) 沒有紀錄，不發送收盤摘要。\n", SessionName(session), open.Format(time.DateOnly))
+				return "", false
+			}
+			return summary.Message(), true
+		})
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSessionSummary(t *testing.T) {
+	morning := time.Date(2026, 10, 16, 8, 45, 0, 0, loc)
+	night := time.Date(2026, 10, 15, 15, 0, 0, 0, loc)
+	tick := func(open time.Time, min int, spot, future float64, spotSource string) Tick {
+		return Tick{Time: open.Add(time.Duration(min) * time.Minute), Spot: spot, Future: future, Diff: spot - future, SpotSource: spotSource}
+	}
+
+	tests := []struct {
+		name    string
+		session string
+		open    time.Time
+		ticks   []Tick
+		data    *Data
+		want    *SessionSummary
+		wantMsg []string // 通知應包含的關鍵字
+	}{
+		{
+			name:    "早盤_盤前加權不列入開高低收",
+			session: SessionMorning,
+			open:    morning,
+			ticks: []Tick{
+				tick(morning, 5, 27000, 27050, spotFallbackSource),
+				tick(morning, 20, 27010, 26950, "twse"),
+				tick(morning, 60, 27100, 27080, "twse"),
+				tick(morning, 300, 27060, 27000, "twse"),
+			},
+			data: &Data{StatsSession: "2026-10-16 Morning", StatsAlerts: 2, StatsErrors: 1, StatsLastError: "連線逾時"},
+			want: &SessionSummary{
+				Session: SessionMorning, Day: "2026-10-16", Ticks: 4,
+				Spot:      OHLC{Open: 27010, High: 27100, Low: 27010, Close: 27060},
+				Future:    OHLC{Open: 27050, High: 27080, Low: 26950, Close: 27000},
+				CloseDiff: 60, MaxDiff: 60, MinDiff: -50,
+				Alerts: 2, Errors: 1, LastError: "連線逾時",
+			},
+			wantMsg: []string{"早盤收盤摘要", "加權: 開 27010.00 高 27100.00 低 27010.00 收 27060.00", "收盤價差: 60.00 (區間: -50.00 ~ 60.00)", "行情通知: 2 次", "系統異常: 1 次"},
+		},
+		{
+			name:    "夜盤_統計屬於其他盤別時不列入",
+			session: SessionNight,
+			open:    night,
+			ticks: []Tick{
+				tick(night, 0, 27000, 27100, spotFallbackSource),
+				tick(night, 600, 27000, 26900, spotFallbackSource),
+			},
+			data: &Data{StatsSession: "2026-10-16 Morning", StatsAlerts: 3},
+			want: &SessionSummary{
+				Session: SessionNight, Day: "2026-10-15", Ticks: 2,
+				Future:    OHLC{Open: 27100, High: 27100, Low: 26900, Close: 26900},
+				CloseDiff: 100, MaxDiff: 100, MinDiff: -100,
+			},
+			wantMsg: []string{"夜盤收盤摘要", "期貨: 開 27100.00", "以加權收盤計", "行情通知: 0 次", "系統異常: 無"},
+		},
+		{
+			name:    "沒有歷史紀錄_以狀態替代",
+			session: SessionMorning,
+			open:    morning,
+			data: &Data{
+				TradingDay: "2026-10-16", SpotHigh: 27100, SpotLow: 27000, FutureHigh: 27080, FutureLow: 26950,
+				LastTWIIValue: 27060, LastFutureValue: 27000, LastDiffValue: 60,
+			},
+			want: &SessionSummary{
+				Session: SessionMorning, Day: "2026-10-16",
+				Spot:      OHLC{High: 27100, Low: 27000, Close: 27060},
+				Future:    OHLC{High: 27080, Low: 26950, Close: 27000},
+				CloseDiff: 60,
+			},
+			wantMsg: []string{"加權: 開 - 高 27100.00", "無歷史紀錄"},
+		},
+		{
+			name:    "沒有該盤的紀錄_不發送",
+			session: SessionNight,
+			open:    night,
+			data:    &Data{NightTradingDay: "2026-10-14", NightFutureHigh: 27000, NightFutureLow: 26900},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NewSessionSummary(tt.session, tt.open, tt.ticks, tt.data)
+			if ok != (tt.want != nil) {
+				t.Fatalf("NewSessionSummary() ok = %v, want %v", ok, tt.want != nil)
+			}
+			if !ok {
+				return
+			}
+			if *got != *tt.want {
+				t.Errorf("NewSessionSummary() = %+v, want %+v", *got, *tt.want)
+			}
+			msg := got.Message()
+			for _, want := range tt.wantMsg {
+				if !strings.Contains(msg, want) {
+					t.Errorf("Message() = %q, want substring %q", msg, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunner_RunOnce_Summary(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 10, 16, 10, 0, 0, 0, loc)) // 週五
+	mem := NewMemoryStore()
+	mem.Save(context.Background(), &Data{LastTWIIValue: 27000, TradingDay: "2026-10-16", SpotHigh: 27100, SpotLow: 26900})
+	notifier := &recordNotifier{}
+	source := &fakeSource{name: "fake", spot: 27010, future: 26900}
+
+	r := NewRunner(newTestConfig(), StaticSources{source}, mem, notifier)
+	r.Clock = clock
+	r.History = NewMemoryHistory()
+	r.Retry = Backoff{Attempts: 1}
+
+	// 盤中: 逆價差過大 (通知) -> 抓取失敗 (系統異常) -> 恢復 (系統恢復)
+	// 收盤後: 排程觸發三次，只發送一次收盤摘要
+	runs := []struct {
+		at      time.Time
+		prepare func()
+	}{
+		{time.Date(2026, 10, 16, 10, 0, 0, 0, loc), func() {}},
+		{time.Date(2026, 10, 16, 10, 5, 0, 0, loc), func() { source.futureErr = errors.New("連線逾時") }},
+		{time.Date(2026, 10, 16, 10, 10, 0, 0, loc), func() { source.futureErr = nil; source.future = 26905 }},
+		{time.Date(2026, 10, 16, 13, 50, 0, 0, loc), func() {}},
+		{time.Date(2026, 10, 16, 13, 55, 0, 0, loc), func() {}},
+		{time.Date(2026, 10, 16, 14, 0, 0, 0, loc), func() {}},
+	}
+	for _, run := range runs {
+		clock.Set(run.at)
+		run.prepare()
+		if err := r.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce() at %v unexpected error: %v", run.at, err)
+		}
+	}
+
+	var summaries []string
+	for _, msg := range notifier.msgs {
+		if strings.Contains(msg, "收盤摘要") {
+			summaries = append(summaries, msg)
+		}
+	}
+	if len(summaries) != 1 {
+		t.Fatalf("RunOnce() summaries = %q, want exactly 1", summaries)
+	}
+	for _, want := range []string{
+		"早盤收盤摘要] 2026-10-16",
+		"期貨: 開 26900.00 高 26905.00 低 26900.00 收 26905.00",
+		"收盤價差: 105.00 (區間: 105.00 ~ 110.00)",
+		"行情通知: 1 次",
+		"系統異常: 1 次",
+		"連線逾時",
+	} {
+		if !strings.Contains(summaries[0], want) {
+			t.Errorf("RunOnce() summary = %q, want substring %q", summaries[0], want)
+		}
+	}
+	if saved, _ := mem.Load(context.Background()); saved.LastSummary != "2026-10-16 Morning" {
+		t.Errorf("RunOnce() saved LastSummary = %q, want %q", saved.LastSummary, "2026-10-16 Morning")
+	}
+}
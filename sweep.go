@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// sweep 子指令: 以歷史報價掃描 THRESHOLD / THRESHOLD_CHANGED 的組合，比較通知數量與後續行情
+//
+// 用法: watchtwii sweep [-csv ticks.csv | -from 2026-10-01 -to 2026-10-16]
+//       [-thresholds 50:100:10] [-changes 20,35,50] [-horizon 30m] [-move 100] [-out sweep.csv]
+
+// SweepResult 單一參數組合的重播統計
+type SweepResult struct {
+	Threshold        float64
+	ThresholdChanged float64
+	Alerts           int // 通知總數
+	Morning          int // 早盤通知數
+	Night            int // 夜盤通知數
+	FollowedByMove   int // 通知後 horizon 內期貨波動達 move 點的通知數
+}
+
+// HitRate 通知後出現大幅波動的比例 (0 ~ 1)
+func (r SweepResult) HitRate() float64 {
+	if r.Alerts == 0 {
+		return 0
+	}
+	return float64(r.FollowedByMove) / float64(r.Alerts)
+}
+
+// Sweep 以每組閾值重播 ticks (見 Replay)，統計通知數量與通知後 horizon 內期貨是否波動達 move 點
+func Sweep(cfg *Config, ticks []Tick, thresholds, changes []float64, horizon time.Duration, move float64) []SweepResult {
+	// 期貨走勢 (依時間排序)，用來判斷通知後的波動
+	var futures []Tick
+	for _, t := range ticks {
+		if t.Future > 0 {
+			futures = append(futures, t)
+		}
+	}
+	sort.SliceStable(futures, func(i, j int) bool { return futures[i].Time.Before(futures[j].Time) })
+
+	var results []SweepResult
+	for _, th := range thresholds {
+		for _, ch := range changes {
+			rc := *cfg
+			rc.Threshold, rc.ThresholdChanged = th, ch
+			replay := Replay(&rc, ticks, io.Discard) // 只需要通知數，不輸出判斷過程
+
+			res := SweepResult{Threshold: th, ThresholdChanged: ch, Alerts: replay.Total()}
+			for _, a := range replay.Alerts {
+				switch a.Session {
+				case SessionMorning:
+					res.Morning++
+				case SessionNight:
+					res.Night++
+				}
+				if largeMoveAfter(futures, a, horizon, move) {
+					res.FollowedByMove++
+				}
+			}
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+// largeMoveAfter 通知後 (不含當下) horizon 內的期貨與通知當下相差是否達 move 點
+func largeMoveAfter(futures []Tick, a ReplayAlert, horizon time.Duration, move float64) bool {
+	end := a.Time.Add(horizon)
+	i := sort.Search(len(futures), func(i int) bool { return futures[i].Time.After(a.Time) })
+	for ; i < len(futures) && !futures[i].Time.After(end); i++ {
+		if math.Abs(futures[i].Future-a.Future) >= move {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintSweep 以表格輸出掃描結果
+func PrintSweep(w io.Writer, results []SweepResult, horizon time.Duration, move float64) {
+	fmt.Fprintf(w, "=== 閾值掃描 (大幅波動: %s 內期貨變動 %.0f 點以上) ===\n", horizon, move)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "THRESHOLD\tTHRESHOLD_CHANGED\t通知數\t早盤\t夜盤\t後續大幅波動\t比例\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%.2f\t%.2f\t%d\t%d\t%d\t%d\t%.1f%%\t\n",
+			r.Threshold, r.ThresholdChanged, r.Alerts, r.Morning, r.Night, r.FollowedByMove, r.HitRate()*100)
+	}
+	tw.Flush()
+}
+
+// WriteSweepCSV 以 CSV 輸出掃描結果
+func WriteSweepCSV(w io.Writer, results []SweepResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"threshold", "threshold_changed", "alerts", "morning", "night", "followed_by_move", "hit_rate"})
+	for _, r := range results {
+		cw.Write([]string{
+			strconv.FormatFloat(r.Threshold, 'f', -1, 64),
+			strconv.FormatFloat(r.ThresholdChanged, 'f', -1, 64),
+			strconv.Itoa(r.Alerts),
+			strconv.Itoa(r.Morning),
+			strconv.Itoa(r.Night),
+			strconv.Itoa(r.FollowedByMove),
+			strconv.FormatFloat(r.HitRate(), 'f', 4, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ParseGrid 解析掃描的數值清單，以逗號分隔，每一項可為單一數值或 起點:終點:間隔 (含終點)
+// 例如: "50,70,100" 或 "50:100:10"
+func ParseGrid(s string) ([]float64, error) {
+	var values []float64
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		nums := make([]float64, len(parts))
+		for i, p := range parts {
+			v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil || v < 0 {
+				return nil, fmt.Errorf("無法解析 %q", item)
+			}
+			nums[i] = v
+		}
+
+		switch len(nums) {
+		case 1:
+			values = append(values, nums[0])
+		case 3:
+			start, end, step := nums[0], nums[1], nums[2]
+			if step <= 0 || end < start {
+				return nil, fmt.Errorf("範圍設定錯誤 %q (需為 起點:終點:間隔)", item)
+			}
+			// 以整數次數累加，避免浮點誤差漏掉終點
+			for i := 0; start+float64(i)*step <= end+step/1e6; i++ {
+				values = append(values, start+float64(i)*step)
+			}
+		default:
+			return nil, fmt.Errorf("無法解析 %q (需為 數值 或 起點:終點:間隔)", item)
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("沒有任何數值")
+	}
+	return values, nil
+}
+
+func runSweep(args []string) error {
+	// sweep 不需要 Telegram 等設定，只載入環境變數中的休市日與歷史紀錄設定
+	godotenv.Load()
+	cfg, err := loadEnvConfig()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+	csvPath := fs.String("csv", "", "歷史報價 CSV (欄位: time,spot,future[,contract])，未指定時讀取歷史紀錄")
+	from := fs.String("from", "", "歷史紀錄的起始交易日 (2006-01-02)")
+	to := fs.String("to", "", "歷史紀錄的結束交易日 (含，預設同 -from)")
+	thresholds := fs.String("thresholds", "50:100:10", "THRESHOLD 候選值 (逗號分隔，或 起點:終點:間隔)")
+	changes := fs.String("changes", "20:50:5", "THRESHOLD_CHANGED 候選值 (逗號分隔，或 起點:終點:間隔)")
+	horizon := fs.Duration("horizon", 30*time.Minute, "通知後觀察多久的期貨走勢")
+	move := fs.Float64("move", 100, "觀察期間內期貨變動幾點以上視為大幅波動")
+	out := fs.String("out", "", "另存 CSV 的路徑")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	thresholdGrid, err := ParseGrid(*thresholds)
+	if err != nil {
+		return fmt.Errorf("-thresholds 設定錯誤: %w", err)
+	}
+	changeGrid, err := ParseGrid(*changes)
+	if err != nil {
+		return fmt.Errorf("-changes 設定錯誤: %w", err)
+	}
+	if *horizon <= 0 || *move <= 0 {
+		return fmt.Errorf("-horizon 與 -move 必須大於 0")
+	}
+
+	ticks, err := loadReplayTicks(context.Background(), cfg, *csvPath, *from, *to)
+	if err != nil {
+		return err
+	}
+	if len(ticks) == 0 {
+		return fmt.Errorf("沒有可重播的報價")
+	}
+
+	results := Sweep(cfg, ticks, thresholdGrid, changeGrid, *horizon, *move)
+	PrintSweep(os.Stdout, results, *horizon, *move)
+
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("建立 CSV 失敗: %w", err)
+		}
+		defer file.Close()
+		if err := WriteSweepCSV(file, results); err != nil {
+			return fmt.Errorf("寫入 CSV 失敗: %w", err)
+		}
+		fmt.Printf("\n✅ 已輸出 %s\n", *out)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGrid(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []float64
+		wantErr bool
+	}{
+		{name: "逗號分隔", input: "50, 70,100", want: []float64{50, 70, 100}},
+		{name: "範圍_含終點", input: "20:50:10", want: []float64{20, 30, 40, 50}},
+		{name: "小數間隔", input: "0.5:1.5:0.5", want: []float64{0.5, 1, 1.5}},
+		{name: "混合", input: "10,20:30:5", want: []float64{10, 20, 25, 30}},
+		{name: "非數值", input: "abc", wantErr: true},
+		{name: "間隔為0", input: "10:20:0", wantErr: true},
+		{name: "終點小於起點", input: "30:20:5", wantErr: true},
+		{name: "缺少間隔", input: "10:20", wantErr: true},
+		{name: "空字串", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGrid(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGrid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGrid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSweep(t *testing.T) {
+	ticks := loadReplayFixture(t)
+	cfg := newTestConfig()
+
+	// 每組的通知數與 TestReplay 的重播結果一致
+	got := Sweep(cfg, ticks, []float64{40, 100}, []float64{20, 60}, 30*time.Minute, 80)
+	want := []SweepResult{
+		{Threshold: 40, ThresholdChanged: 20, Alerts: 8, Morning: 5, Night: 3, FollowedByMove: 2},
+		{Threshold: 40, ThresholdChanged: 60, Alerts: 4, Morning: 1, Night: 3, FollowedByMove: 0},
+		{Threshold: 100, ThresholdChanged: 20, Alerts: 5, Morning: 2, Night: 3, FollowedByMove: 2},
+		{Threshold: 100, ThresholdChanged: 60, Alerts: 3, Morning: 0, Night: 3, FollowedByMove: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Sweep() = %+v, want %+v", got, want)
+	}
+	if cfg.Threshold != 70 || cfg.ThresholdChanged != 35 {
+		t.Errorf("Sweep() 不應修改傳入的設定: %+v", cfg)
+	}
+
+	var out bytes.Buffer
+	if err := WriteSweepCSV(&out, got[:1]); err != nil {
+		t.Fatalf("WriteSweepCSV() unexpected error: %v", err)
+	}
+	wantCSV := "threshold,threshold_changed,alerts,morning,night,followed_by_move,hit_rate\n40,20,8,5,3,2,0.2500\n"
+	if out.String() != wantCSV {
+		t.Errorf("WriteSweepCSV() = %q, want %q", out.String(), wantCSV)
+	}
+
+	out.Reset()
+	PrintSweep(&out, got, 30*time.Minute, 80)
+	if !strings.Contains(out.String(), "25.0%") {
+		t.Errorf("PrintSweep() = %q, want hit rate", out.String())
+	}
+}
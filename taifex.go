@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TAIFEX 行情資訊網 (MIS) 報價 API
+const TAIFEXMisURL = "https://mis.taifex.com.tw/futures/api/getQuoteList"
+
+// 期貨月份代碼 (1 月 = A ... 12 月 = L)
+const futureMonthCodes = "ABCDEFGHIJKL"
+
+// SettlementDate 取得指定年月的台指期最後結算日 (該月第三個星期三)
+func SettlementDate(year int, month time.Month, loc *time.Location) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	offset := (int(time.Wednesday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+14)
+}
+
+// tradingDate 夜盤 00:00 ~ 05:00 仍屬於前一天開盤的交易時段
+func tradingDate(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	if t.Hour()*100+t.Minute() <= 500 {
+		t = t.AddDate(0, 0, -1)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// prevTradingDate t 所屬交易日的前一個交易日 (略過週末與 holidays 中的休市日)
+func prevTradingDate(t time.Time, holidays string, loc *time.Location) time.Time {
+	day := tradingDate(t, loc)
+	for {
+		day = day.AddDate(0, 0, -1)
+		if wd := day.Weekday(); wd != time.Saturday && wd != time.Sunday && !isDateInList(holidays, day) {
+			return day
+		}
+	}
+}
+
+// NearMonthContract 取得指定時間的台指期近月合約代碼 (例如: TXFK5)
+// 結算日 (第三個星期三) 當天起改為下個月份合約
+func NearMonthContract(t time.Time, loc *time.Location) string {
+	day := tradingDate(t, loc)
+	year, month := day.Year(), day.Month()
+	if !day.Before(SettlementDate(year, month, loc)) {
+		next := time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+		year, month = next.Year(), next.Month()
+	}
+	return fmt.Sprintf("TXF%c%d", futureMonthCodes[month-1], year%10)
+}
+
+// taifexQuoteRequest getQuoteList 查詢條件
+type taifexQuoteRequest struct {
+	MarketType  string // 0: 一般交易時段, 1: 盤後交易時段
+	SymbolType  string // F: 期貨
+	KindID      string
+	CID         string // 商品代號 (TXF)
+	ExpireMonth string
+	RowSize     string
+	PageNo      string
+	SortColumn  string
+	AscDesc     string
+}
+
+// taifexQuoteResponse getQuoteList 回傳格式 (僅列出用到的欄位)
+type taifexQuoteResponse struct {
+	RtCode string `json:"RtCode"`
+	RtMsg  string `json:"RtMsg"`
+	RtData struct {
+		QuoteList []struct {
+			SymbolID   string `json:"SymbolID"`   // 例如: TXFK5-F (一般) / TXFK5-M (盤後)
+			DispCName  string `json:"DispCName"`  // 顯示名稱
+			CLastPrice string `json:"CLastPrice"` // 成交價
+			CRefPrice  string `json:"CRefPrice"`  // 參考價
+			CDate      string `json:"CDate"`      // 日期 (20060102)
+			CTime      string `json:"CTime"`      // 時間 (150405)
+		} `json:"QuoteList"`
+	} `json:"RtData"`
+}
+
+// TAIFEXSource 透過期交所行情 API 取得台指期近月合約 (不提供現貨)
+type TAIFEXSource struct {
+	URL     string
+	Fetcher *Fetcher
+	Clock   Clock // 決定近月合約與盤別
+}
+
+func NewTAIFEXSource(f *Fetcher) *TAIFEXSource {
+	return &TAIFEXSource{
+		URL:     TAIFEXMisURL,
+		Fetcher: f,
+		Clock:   SystemClock{},
+	}
+}
+
+func (s *TAIFEXSource) Name() string {
+	return "taifex"
+}
+
+func (s *TAIFEXSource) QuotesSpot() bool   { return false }
+func (s *TAIFEXSource) QuotesFuture() bool { return true }
+
+func (s *TAIFEXSource) FetchSpot(ctx context.Context) (Quote, error) {
+	return Quote{}, ErrQuoteNotSupported
+}
+
+func (s *TAIFEXSource) FetchFuture(ctx context.Context) (Quote, error) {
+	now := s.Clock.Now().In(loc)
+
+	marketType := "0"
+	if hm := now.Hour()*100 + now.Minute(); hm >= 1500 || hm <= 500 {
+		marketType = "1"
+	}
+
+	reqBody, err := json.Marshal(taifexQuoteRequest{
+		MarketType: marketType,
+		SymbolType: "F",
+		KindID:     "1",
+		CID:        "TXF",
+		RowSize:    "全部",
+		AscDesc:    "A",
+	})
+	if err != nil {
+		return Quote{}, err
+	}
+
+	raw, err := s.Fetcher.Post(ctx, s.URL, "application/json", reqBody)
+	if err != nil {
+		return Quote{}, fmt.Errorf("載入 URL 失敗: %v", err)
+	}
+
+	var body taifexQuoteResponse
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return Quote{}, fmt.Errorf("解析 TAIFEX JSON 失敗: %w", err)
+	}
+	if body.RtCode != "0" {
+		return Quote{}, fmt.Errorf("TAIFEX 回應錯誤 (%s): %s", body.RtCode, body.RtMsg)
+	}
+
+	contract := NearMonthContract(now, loc)
+	for _, item := range body.RtData.QuoteList {
+		if len(item.SymbolID) < len(contract) || item.SymbolID[:len(contract)] != contract {
+			continue
+		}
+
+		val, err := ParseToFloat(item.CLastPrice)
+		if err != nil {
+			return Quote{}, fmt.Errorf("解析 %s 成交價失敗: %w", contract, err)
+		}
+
+		quoteTime, err := time.ParseInLocation("20060102150405", item.CDate+item.CTime, loc)
+		if err != nil {
+			quoteTime = now
+		}
+
+		return Quote{Value: val, Time: quoteTime, Source: s.Name(), Contract: contract, Raw: item.CLastPrice}, nil
+	}
+
+	return Quote{}, fmt.Errorf("找不到近月合約 %s 的報價", contract)
+}
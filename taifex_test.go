@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNearMonthContract(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"結算日前一天", time.Date(2025, 10, 14, 10, 0, 0, 0, loc), "TXFJ5"},
+		{"結算日前一天夜盤跨日_仍為當月", time.Date(2025, 10, 15, 3, 0, 0, 0, loc), "TXFJ5"},
+		{"結算日早盤_切換次月", time.Date(2025, 10, 15, 9, 0, 0, 0, loc), "TXFK5"},
+		{"結算日夜盤_次月", time.Date(2025, 10, 15, 15, 0, 0, 0, loc), "TXFK5"},
+		{"十二月結算後_跨年", time.Date(2025, 12, 17, 9, 0, 0, 0, loc), "TXFA6"},
+		{"月初", time.Date(2026, 1, 2, 9, 0, 0, 0, loc), "TXFA6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NearMonthContract(tt.now, loc); got != tt.want {
+				t.Errorf("NearMonthContract(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettlementDate(t *testing.T) {
+	tests := []struct {
+		year  int
+		month time.Month
+		want  string
+	}{
+		{2025, time.October, "2025-10-15"},
+		{2025, time.December, "2025-12-17"},
+		{2026, time.January, "2026-01-21"},
+		{2026, time.April, "2026-04-15"},
+	}
+
+	for _, tt := range tests {
+		if got := SettlementDate(tt.year, tt.month, loc).Format("2006-01-02"); got != tt.want {
+			t.Errorf("SettlementDate(%d, %v) = %v, want %v", tt.year, tt.month, got, tt.want)
+		}
+	}
+}
+
+func TestTAIFEXSource_FetchFuture(t *testing.T) {
+	srv := newFixtureServer(t, "testdata/taifex_quotes.json")
+
+	tests := []struct {
+		name         string
+		now          time.Time
+		wantValue    float64
+		wantContract string
+	}{
+		{"近月_十月合約", time.Date(2025, 10, 14, 9, 30, 0, 0, loc), 27230, "TXFJ5"},
+		{"結算日_改用十一月合約", time.Date(2025, 10, 15, 9, 30, 0, 0, loc), 27188, "TXFK5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &TAIFEXSource{URL: srv.URL, Fetcher: newTestFetcher(), Clock: NewFakeClock(tt.now)}
+
+			q, err := src.FetchFuture(context.Background())
+			if err != nil {
+				t.Fatalf("FetchFuture() unexpected error: %v", err)
+			}
+			if q.Value != tt.wantValue || q.Contract != tt.wantContract {
+				t.Errorf("FetchFuture() = (%.2f, %s), want (%.2f, %s)", q.Value, q.Contract, tt.wantValue, tt.wantContract)
+			}
+		})
+	}
+
+	// 找不到近月合約
+	src := &TAIFEXSource{URL: srv.URL, Fetcher: newTestFetcher(), Clock: NewFakeClock(time.Date(2026, 3, 2, 9, 30, 0, 0, loc))}
+	if _, err := src.FetchFuture(context.Background()); err == nil {
+		t.Errorf("FetchFuture() want error for missing contract")
+	}
+}
+
+func TestData_CheckRollover(t *testing.T) {
+	tests := []struct {
+		name         string
+		session      string
+		now          time.Time
+		d            *Data
+		contract     string  // 來源回報的合約 (空字串代表以結算日曆推算)
+		spotVal      float64 // 轉倉當下的加權
+		futureVal    float64 // 轉倉當下的期貨
+		nextFuture   float64 // 下一次抓取的期貨
+		wantRollover bool
+		wantContract string
+	}{
+		{
+			name:    "早盤_結算日來源回報新合約_轉倉",
+			session: SessionMorning,
+			now:     time.Date(2025, 10, 15, 8, 45, 0, 0, loc),
+			d: &Data{
+				LastTWIIValue: 27200, LastDiffValue: -10, FutureContract: "TXFJ5",
+				SpotHigh: 27250, SpotLow: 27100, FutureHigh: 27260, FutureLow: 27110,
+			},
+			contract:     "TXFK5",
+			spotVal:      27200,
+			futureVal:    27120, // 次月合約逆價差 80 點
+			nextFuture:   27125,
+			wantRollover: true,
+			wantContract: "TXFK5",
+		},
+		{
+			name:    "夜盤_結算日前夜_依日曆仍為當月_不轉倉",
+			session: SessionNight,
+			now:     time.Date(2025, 10, 15, 3, 0, 0, 0, loc),
+			d: &Data{
+				LastTWIIValue: 27200, LastDiffValue: -10, FutureContract: "TXFJ5",
+				FutureHigh: 27260, FutureLow: 27110,
+			},
+			spotVal:      27200,
+			futureVal:    27215,
+			wantRollover: false,
+			wantContract: "TXFJ5",
+		},
+		{
+			name:    "夜盤_結算日夜盤_依日曆切換次月_轉倉",
+			session: SessionNight,
+			now:     time.Date(2025, 10, 15, 15, 0, 0, 0, loc),
+			d: &Data{
+				LastTWIIValue: 27200, LastDiffValue: -10, FutureContract: "TXFJ5",
+				FutureHigh: 27260, FutureLow: 27190,
+			},
+			spotVal:      27200,
+			futureVal:    27100, // 次月合約，低於舊合約的夜盤低點
+			nextFuture:   27100, // 持平 (任何變動都會是新合約的新高/新低)
+			wantRollover: true,
+			wantContract: "TXFK5",
+		},
+		{
+			name:         "第一次運行_沒有合約紀錄_不轉倉",
+			session:      SessionMorning,
+			now:          time.Date(2025, 10, 15, 9, 0, 0, 0, loc),
+			d:            &Data{LastTWIIValue: 27200},
+			contract:     "TXFK5",
+			spotVal:      27200,
+			futureVal:    27120,
+			wantRollover: false,
+			wantContract: "TXFK5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotMsg, gotRollover := tt.d.CheckRollover(tt.contract, tt.futureVal, tt.now, loc)
+
+			if gotRollover != tt.wantRollover {
+				t.Fatalf("CheckRollover() rollover = %v, want %v (msg: %s)", gotRollover, tt.wantRollover, gotMsg)
+			}
+			if tt.d.FutureContract != tt.wantContract {
+				t.Errorf("CheckRollover() contract = %v, want %v", tt.d.FutureContract, tt.wantContract)
+			}
+			if !gotRollover {
+				return
+			}
+
+			if tt.d.FutureHigh != tt.futureVal || tt.d.FutureLow != tt.futureVal {
+				t.Errorf("CheckRollover() high/low = (%.2f, %.2f), want reset to %.2f", tt.d.FutureHigh, tt.d.FutureLow, tt.futureVal)
+			}
+
+			// 轉倉後的下一次抓取不應因月份價差觸發價差警示
+			tt.d.LastDiffValue = tt.spotVal - tt.futureVal
+			msg, err := NewMessage(tt.session, os.Stdout)
+			if err != nil {
+				t.Fatalf("NewMessage failed: %v", err)
+			}
+			if nextMsg, notify := msg.Build(tt.d, tt.spotVal, tt.nextFuture, 50, 35); notify {
+				t.Errorf("Build() after rollover notify = true, want false (msg: %s)", nextMsg)
+			}
+		})
+	}
+}
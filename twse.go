@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TWSE 基本市況報導 (MIS) 的即時報價 API
+const TWSEMisURL = "https://mis.twse.com.tw/stock/api/getStockInfo.jsp?ex_ch=tse_t00.tw&json=1&delay=0"
+
+// 加權指數交易狀態
+const (
+	TWSEStatusPreOpen = "PreOpen" // 尚未開盤 (無成交價)
+	TWSEStatusTrading = "Trading" // 盤中
+	TWSEStatusClosed  = "Closed"  // 已收盤
+)
+
+// twseMisResponse getStockInfo.jsp 回傳格式 (僅列出用到的欄位)
+type twseMisResponse struct {
+	RtCode    string `json:"rtcode"`
+	RtMessage string `json:"rtmessage"`
+	MsgArray  []struct {
+		Code      string `json:"c"`     // 代號 (t00)
+		Name      string `json:"n"`     // 名稱
+		Price     string `json:"z"`     // 最近成交價 ("-" 代表尚未成交)
+		PrevClose string `json:"y"`     // 昨收
+		Date      string `json:"d"`     // 日期 (20060102)
+		Time      string `json:"t"`     // 時間 (15:04:05)
+		TLong     string `json:"tlong"` // 報價時間 (Unix 毫秒)
+	} `json:"msgArray"`
+}
+
+// TWSEQuote 加權指數即時報價
+type TWSEQuote struct {
+	Price     float64
+	PrevClose float64
+	Time      time.Time
+	Status    string
+	Raw       string // 成交價原始字串
+}
+
+// TWSESource 透過 TWSE MIS JSON API 取得加權指數 (不提供期貨)
+type TWSESource struct {
+	URL     string
+	Fetcher *Fetcher
+}
+
+func NewTWSESource(f *Fetcher) *TWSESource {
+	return &TWSESource{
+		URL:     TWSEMisURL,
+		Fetcher: f,
+	}
+}
+
+func (s *TWSESource) Name() string {
+	return "twse"
+}
+
+func (s *TWSESource) QuotesSpot() bool   { return true }
+func (s *TWSESource) QuotesFuture() bool { return false }
+
+func (s *TWSESource) FetchSpot(ctx context.Context) (Quote, error) {
+	q, err := s.FetchIndex(ctx)
+	if err != nil {
+		return Quote{}, err
+	}
+	if q.Status == TWSEStatusPreOpen {
+		return Quote{}, fmt.Errorf("加權指數尚未開盤 (昨收: %.2f)", q.PrevClose)
+	}
+	return Quote{Value: q.Price, Time: q.Time, Source: s.Name(), Raw: q.Raw}, nil
+}
+
+func (s *TWSESource) FetchFuture(ctx context.Context) (Quote, error) {
+	return Quote{}, ErrQuoteNotSupported
+}
+
+// FetchIndex 取得並解析加權指數即時報價
+func (s *TWSESource) FetchIndex(ctx context.Context) (*TWSEQuote, error) {
+	raw, err := s.Fetcher.Get(ctx, s.URL)
+	if err != nil {
+		return nil, fmt.Errorf("載入 URL 失敗: %v", err)
+	}
+
+	var body twseMisResponse
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, fmt.Errorf("解析 TWSE JSON 失敗: %w", err)
+	}
+
+	return body.quote()
+}
+
+func (r *twseMisResponse) quote() (*TWSEQuote, error) {
+	if r.RtCode != "0000" {
+		return nil, fmt.Errorf("TWSE 回應錯誤 (%s): %s", r.RtCode, r.RtMessage)
+	}
+	if len(r.MsgArray) == 0 {
+		return nil, fmt.Errorf("TWSE 回應沒有報價資料")
+	}
+
+	m := r.MsgArray[0]
+	q := &TWSEQuote{}
+
+	var err error
+	if q.PrevClose, err = ParseToFloat(m.PrevClose); err != nil {
+		return nil, fmt.Errorf("解析昨收失敗: %w", err)
+	}
+
+	if ms, err := strconv.ParseInt(m.TLong, 10, 64); err == nil && ms > 0 {
+		q.Time = time.UnixMilli(ms).In(loc)
+	} else if q.Time, err = time.ParseInLocation("20060102 15:04:05", m.Date+" "+m.Time, loc); err != nil {
+		return nil, fmt.Errorf("解析報價時間失敗: %w", err)
+	}
+
+	q.Raw = m.Price
+	if m.Price == "" || m.Price == "-" {
+		q.Status = TWSEStatusPreOpen
+		return q, nil
+	}
+	if q.Price, err = ParseToFloat(m.Price); err != nil {
+		return nil, fmt.Errorf("解析成交價失敗: %w", err)
+	}
+
+	// 現貨 13:30 收盤
+	q.Status = TWSEStatusTrading
+	if q.Time.Hour()*100+q.Time.Minute() >= 1330 {
+		q.Status = TWSEStatusClosed
+	}
+
+	return q, nil
+}
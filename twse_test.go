@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newFixtureServer 以 testdata 中的檔案作為回應內容
+func newFixtureServer(t *testing.T, fixture string) *httptest.Server {
+	t.Helper()
+	body, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatalf("讀取測試資料失敗: %v", err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTWSESource_FetchIndex(t *testing.T) {
+	tests := []struct {
+		name          string
+		fixture       string
+		wantPrice     float64
+		wantPrevClose float64
+		wantStatus    string
+		wantTime      string // 15:04
+		wantErr       string
+	}{
+		{
+			name:          "盤中",
+			fixture:       "testdata/twse_trading.json",
+			wantPrice:     27201.64,
+			wantPrevClose: 27133.93,
+			wantStatus:    TWSEStatusTrading,
+			wantTime:      "09:30",
+		},
+		{
+			name:          "盤前_無成交價",
+			fixture:       "testdata/twse_preopen.json",
+			wantPrevClose: 27133.93,
+			wantStatus:    TWSEStatusPreOpen,
+			wantTime:      "08:45",
+		},
+		{
+			name:          "收盤",
+			fixture:       "testdata/twse_closed.json",
+			wantPrice:     27286.22,
+			wantPrevClose: 27133.93,
+			wantStatus:    TWSEStatusClosed,
+			wantTime:      "13:30",
+		},
+		{
+			name:    "查無資料",
+			fixture: "testdata/twse_error.json",
+			wantErr: "Information Not Found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFixtureServer(t, tt.fixture)
+			src := &TWSESource{URL: srv.URL, Fetcher: newTestFetcher()}
+
+			q, err := src.FetchIndex(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("FetchIndex() error = %v, want substring %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchIndex() unexpected error: %v", err)
+			}
+
+			if q.Price != tt.wantPrice || q.PrevClose != tt.wantPrevClose {
+				t.Errorf("FetchIndex() = (%.2f, %.2f), want (%.2f, %.2f)", q.Price, q.PrevClose, tt.wantPrice, tt.wantPrevClose)
+			}
+			if q.Status != tt.wantStatus {
+				t.Errorf("FetchIndex() status = %v, want %v", q.Status, tt.wantStatus)
+			}
+			if got := q.Time.In(loc).Format("15:04"); got != tt.wantTime {
+				t.Errorf("FetchIndex() time = %v, want %v", got, tt.wantTime)
+			}
+		})
+	}
+}
+
+func TestTWSESource_ScrapeData(t *testing.T) {
+	srv := newFixtureServer(t, "testdata/twse_trading.json")
+
+	// TWSE 只提供現貨，期貨由後面的來源補上
+	sources := []QuoteSource{
+		&TWSESource{URL: srv.URL, Fetcher: newTestFetcher()},
+		&fakeSource{name: "fake", spot: 1, future: 27230},
+	}
+
+	res, err := ScrapeData(context.Background(), sources, ScrapePolicy{})
+	if err != nil {
+		t.Fatalf("ScrapeData() unexpected error: %v", err)
+	}
+	if res.Spot.Value != 27201.64 || res.Spot.Source != "twse" {
+		t.Errorf("ScrapeData() spot = %+v, want 27201.64 from twse", res.Spot)
+	}
+	if !res.Spot.Time.Equal(time.UnixMilli(1760578200000)) {
+		t.Errorf("ScrapeData() spot time = %v, want quote time from TWSE", res.Spot.Time)
+	}
+	if res.Future.Value != 27230 || res.Future.Source != "fake" {
+		t.Errorf("ScrapeData() future = %+v, want 27230 from fake", res.Future)
+	}
+}
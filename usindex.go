@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Yahoo Finance 圖表 API (以指數代號取代 %s)
+const YahooChartURL = "https://query1.finance.yahoo.com/v8/finance/chart/%s?range=1d&interval=1d"
+
+// 預設的美股指數與顯示名稱 (US_INDEX_SYMBOLS 未列出名稱的代號以來源提供的名稱顯示)
+var usIndexNames = map[string]string{
+	"^DJI":  "道瓊",
+	"^GSPC": "S&P 500",
+	"^IXIC": "那斯達克",
+	"^SOX":  "費城半導體",
+}
+
+// IndexQuote 指數收盤報價
+type IndexQuote struct {
+	Symbol    string
+	Name      string
+	Close     float64
+	PrevClose float64
+	Time      time.Time // 報價時間
+}
+
+// Change 漲跌點數
+func (q IndexQuote) Change() float64 {
+	return q.Close - q.PrevClose
+}
+
+// ChangePercent 漲跌幅 (%)
+func (q IndexQuote) ChangePercent() float64 {
+	if q.PrevClose == 0 {
+		return 0
+	}
+	return q.Change() / q.PrevClose * 100
+}
+
+// IndexSource 國際指數報價來源 (盤前簡報用)
+type IndexSource interface {
+	Name() string
+	FetchIndex(ctx context.Context, symbol string) (IndexQuote, error)
+}
+
+// indexSources 可用的美股指數來源 (US_INDEX_SOURCE -> 建構函式)
+var indexSources = map[string]func(cfg *Config, f *Fetcher) IndexSource{
+	"yahoo": func(cfg *Config, f *Fetcher) IndexSource { return NewYahooChartSource(f) },
+}
+
+// NewIndexSource 依 US_INDEX_SOURCE 建立美股指數來源 (none 時回傳 nil，盤前簡報不附美股)
+func NewIndexSource(cfg *Config, f *Fetcher) (IndexSource, error) {
+	name := strings.ToLower(strings.TrimSpace(cfg.USIndexSource))
+	if name == "none" {
+		return nil, nil
+	}
+	factory, ok := indexSources[name]
+	if !ok {
+		return nil, fmt.Errorf("未知的美股指數來源: %s", cfg.USIndexSource)
+	}
+	return factory(cfg, f), nil
+}
+
+// FetchIndices 依序取得各指數 (個別失敗不影響其他指數)
+func FetchIndices(ctx context.Context, src IndexSource, symbols []string) ([]IndexQuote, error) {
+	var quotes []IndexQuote
+	var errs error
+	for _, symbol := range symbols {
+		symbol = strings.TrimSpace(symbol)
+		if symbol == "" {
+			continue
+		}
+		q, err := src.FetchIndex(ctx, symbol)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", symbol, err))
+			continue
+		}
+		if name, ok := usIndexNames[symbol]; ok {
+			q.Name = name
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes, errs
+}
+
+// yahooChartResponse 圖表 API 回傳格式 (僅列出用到的欄位)
+type yahooChartResponse struct {
+	Chart struct {
+		Result []struct {
+			Meta struct {
+				Symbol             string  `json:"symbol"`
+				ShortName          string  `json:"shortName"`
+				RegularMarketPrice float64 `json:"regularMarketPrice"`
+				ChartPreviousClose float64 `json:"chartPreviousClose"`
+				RegularMarketTime  int64   `json:"regularMarketTime"` // Unix 秒
+			} `json:"meta"`
+		} `json:"result"`
+		Error *struct {
+			Code        string `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"chart"`
+}
+
+// YahooChartSource 透過 Yahoo Finance 圖表 API 取得指數收盤
+type YahooChartSource struct {
+	URL     string // 含 %s (指數代號) 的網址
+	Fetcher *Fetcher
+}
+
+func NewYahooChartSource(f *Fetcher) *YahooChartSource {
+	return &YahooChartSource{
+		URL:     YahooChartURL,
+		Fetcher: f,
+	}
+}
+
+func (s *YahooChartSource) Name() string {
+	return "yahoo"
+}
+
+func (s *YahooChartSource) FetchIndex(ctx context.Context, symbol string) (IndexQuote, error) {
+	raw, err := s.Fetcher.Get(ctx, fmt.Sprintf(s.URL, url.PathEscape(symbol)))
+	if err != nil {
+		return IndexQuote{}, fmt.Errorf("載入 URL 失敗: %v", err)
+	}
+
+	var body yahooChartResponse
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return IndexQuote{}, fmt.Errorf("解析 Yahoo JSON 失敗: %w", err)
+	}
+	if e := body.Chart.Error; e != nil {
+		return IndexQuote{}, fmt.Errorf("Yahoo 回應錯誤 (%s): %s", e.Code, e.Description)
+	}
+	if len(body.Chart.Result) == 0 {
+		return IndexQuote{}, fmt.Errorf("Yahoo 回應沒有報價資料")
+	}
+
+	m := body.Chart.Result[0].Meta
+	if m.RegularMarketPrice <= 0 {
+		return IndexQuote{}, fmt.Errorf("Yahoo 回應沒有成交價")
+	}
+	return IndexQuote{
+		Symbol:    symbol,
+		Name:      m.ShortName,
+		Close:     m.RegularMarketPrice,
+		PrevClose: m.ChartPreviousClose,
+		Time:      time.Unix(m.RegularMarketTime, 0),
+	}, nil
+}
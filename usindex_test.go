@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestYahooChartSource_FetchIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		want    IndexQuote
+		wantErr string
+	}{
+		{
+			name:    "道瓊收盤",
+			fixture: "testdata/yahoo_chart_dji.json",
+			want: IndexQuote{
+				Symbol: "^DJI", Name: "Dow Jones Industrial Average",
+				Close: 46190.61, PrevClose: 45952.24,
+				Time: time.Date(2026, 10, 15, 20, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:    "查無代號",
+			fixture: "testdata/yahoo_chart_error.json",
+			wantErr: "No data found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := os.ReadFile(tt.fixture)
+			if err != nil {
+				t.Fatalf("讀取測試資料失敗: %v", err)
+			}
+			var path string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.EscapedPath()
+				w.Write(body)
+			}))
+			defer srv.Close()
+
+			src := &YahooChartSource{URL: srv.URL + "/chart/%s", Fetcher: newTestFetcher()}
+			q, err := src.FetchIndex(context.Background(), "^DJI")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("FetchIndex() error = %v, want substring %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchIndex() unexpected error: %v", err)
+			}
+			if path != "/chart/%5EDJI" {
+				t.Errorf("FetchIndex() path = %q, want escaped symbol", path)
+			}
+			if q.Symbol != tt.want.Symbol || q.Name != tt.want.Name || q.Close != tt.want.Close ||
+				q.PrevClose != tt.want.PrevClose || !q.Time.Equal(tt.want.Time) {
+				t.Errorf("FetchIndex() = %+v, want %+v", q, tt.want)
+			}
+		})
+	}
+}
+
+// fakeIndexSource 固定的指數報價 (未列出的代號回傳錯誤)
+type fakeIndexSource struct {
+	quotes map[string]IndexQuote
+	calls  int
+}
+
+func (s *fakeIndexSource) Name() string {
+	return "fake"
+}
+
+func (s *fakeIndexSource) FetchIndex(ctx context.Context, symbol string) (IndexQuote, error) {
+	s.calls++
+	q, ok := s.quotes[symbol]
+	if !ok {
+		return IndexQuote{}, errors.New("查無代號")
+	}
+	q.Symbol = symbol
+	return q, nil
+}
+
+func TestFetchIndices(t *testing.T) {
+	src := &fakeIndexSource{quotes: map[string]IndexQuote{
+		"^DJI": {Name: "Dow Jones Industrial Average", Close: 46190.61, PrevClose: 45952.24},
+		"TSM":  {Name: "Taiwan Semiconductor", Close: 300, PrevClose: 310},
+	}}
+
+	got, err := FetchIndices(context.Background(), src, []string{"^DJI", " TSM", "^XXX", ""})
+	if err == nil || !strings.Contains(err.Error(), "^XXX") {
+		t.Errorf("FetchIndices() error = %v, want error of ^XXX", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("FetchIndices() = %+v, want 2 quotes", got)
+	}
+	// 預設指數以中文名稱顯示，其餘沿用來源的名稱
+	if got[0].Name != "道瓊" || got[1].Name != "Taiwan Semiconductor" {
+		t.Errorf("FetchIndices() names = (%q, %q), want (道瓊, Taiwan Semiconductor)", got[0].Name, got[1].Name)
+	}
+	if change := got[1].ChangePercent(); change > -3.2 || change < -3.3 {
+		t.Errorf("ChangePercent() = %.4f, want about -3.23", change)
+	}
+}
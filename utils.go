@@ -1,10 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/antchfx/htmlquery"
@@ -18,10 +22,27 @@ const (
 	SessionClosed  = "Closed"  // 休市
 )
 
+// SessionName 盤別的中文名稱
+func SessionName(session string) string {
+	switch session {
+	case SessionMorning:
+		return "早盤"
+	case SessionNight:
+		return "夜盤"
+	default:
+		return "休市"
+	}
+}
+
 // 判斷台股早盤或夜盤
 // 回傳: sessionType (SessionMorning, SessionNight, SessionClosed), isTrading (bool)
-func GetSessionType(loc *time.Location) (string, bool) {
-	now := time.Now().In(loc)
+func GetSessionType(clock Clock, loc *time.Location) (string, bool) {
+	return SessionAt(clock.Now(), loc)
+}
+
+// SessionAt 判斷指定時間的盤別
+func SessionAt(t time.Time, loc *time.Location) (string, bool) {
+	now := t.In(loc)
 
 	hour := now.Hour()
 	minute := now.Minute()
@@ -42,7 +63,7 @@ func GetSessionType(loc *time.Location) (string, bool) {
 
 // IsUSMarketInWinterTime 判斷美股市場當前是否處於標準時間 (冬令時間)。
 // 美股 DST (夏令時間) 通常從三月第二個週日到十一月第一個週日。
-func IsUSMarketInWinterTime() (bool, error) {
+func IsUSMarketInWinterTime(clock Clock) (bool, error) {
 	// 載入美國/紐約時區 (美股交易所時區)
 	nyLoc, err := time.LoadLocation("America/New_York")
 	if err != nil {
@@ -50,7 +71,7 @@ func IsUSMarketInWinterTime() (bool, error) {
 	}
 
 	// 取得當前時間，並將其轉換到紐約時區
-	now := time.Now().In(nyLoc)
+	now := clock.Now().In(nyLoc)
 
 	// time.Time.Zone() 會返回時區名稱和 UTC 偏移量（秒）。
 	// 如果時區名稱包含 "EDT" (夏令時間)，則不是冬令時間。
@@ -71,13 +92,18 @@ func IsUSMarketInWinterTime() (bool, error) {
 
 // IsTodayInDateList 檢查今天是否在指定的日期清單中
 // input: "2025-11-11_2025-12-25"
-func IsTodayInDateList(dateListStr string, loc *time.Location) bool {
+func IsTodayInDateList(clock Clock, dateListStr string, loc *time.Location) bool {
+	return isDateInList(dateListStr, clock.Now().In(loc))
+}
+
+// isDateInList 檢查 t 的日期是否在指定的日期清單中
+func isDateInList(dateListStr string, t time.Time) bool {
 	if dateListStr == "" {
 		return false
 	}
 
-	// 1. 取得指定時區的當天日期字串 (格式: YYYY-MM-DD)
-	todayStr := time.Now().In(loc).Format("2006-01-02")
+	// 1. 取得當天日期字串 (格式: YYYY-MM-DD)
+	todayStr := t.Format("2006-01-02")
 
 	// 2. 將輸入字串拆分成切片
 	dates := strings.Split(dateListStr, ",")
@@ -93,25 +119,25 @@ func IsTodayInDateList(dateListStr string, loc *time.Location) bool {
 	return false
 }
 
-func GetCurrentTime(loc *time.Location) (currentTime int) {
+func GetCurrentTime(clock Clock, loc *time.Location) (currentTime int) {
 
-	now := time.Now().In(loc)
+	now := clock.Now().In(loc)
 
 	// 使用 hour*100 + minute 格式來做快速比較
 	currentTime = now.Hour()*100 + now.Minute()
 	return
 }
 
-func IsTaipexPreOpen(loc *time.Location) bool {
-	currentTime := GetCurrentTime(loc)
+func IsTaipexPreOpen(clock Clock, loc *time.Location) bool {
+	currentTime := GetCurrentTime(clock, loc)
 	return currentTime >= 845 && currentTime <= 900
 }
 
-// 透過 URL 跟 XPath 取得原始字串
-func FetchValueString(urlLink string, xpathStr string) (string, error) {
-	doc, err := htmlquery.LoadURL(urlLink)
+// FindValueString 在 HTML 中以 XPath 取得節點文字
+func FindValueString(body []byte, xpathStr string) (string, error) {
+	doc, err := htmlquery.Parse(bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("載入 URL 失敗: %v", err)
+		return "", fmt.Errorf("解析 HTML 失敗: %v", err)
 	}
 
 	node := htmlquery.FindOne(doc, xpathStr)
@@ -135,22 +161,56 @@ func ParseToFloat(raw string) (float64, error) {
 	return val, nil
 }
 
-// 發送 Telegram 通知
-func SendAlert(tgToken, tgChatIDs, msg string) {
+// contextTransport 讓 telebot 的請求隨 ctx 取消 (telebot 本身不支援 context)
+// ctx 為目前發送中的通知 (由 TelegramNotifier 在每次發送前設定)
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
 
-	pref := tele.Settings{
-		Token:  tgToken,
-		Poller: &tele.LongPoller{Timeout: 10 * time.Second},
-	}
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
 
-	b, err := tele.NewBot(pref)
+// Notifier 通知管道
+type Notifier interface {
+	Notify(ctx context.Context, msg string)
+}
+
+// TelegramNotifier 透過 Telegram Bot 發送通知
+// Bot 只在建立時產生一次，常駐模式的每次通知都重複使用
+type TelegramNotifier struct {
+	ChatIDs string // 逗號分隔的 Chat ID
+
+	bot       *tele.Bot
+	transport *contextTransport
+	mu        sync.Mutex // 一次只發送一則通知 (transport 的 ctx 屬於目前這則)
+}
+
+// NewTelegramNotifier 建立 Telegram 通知
+// 只用來發送訊息: Offline 模式不呼叫 getMe (啟動時不需要連線)，也不啟動 poller
+func NewTelegramNotifier(token, chatIDs string) (*TelegramNotifier, error) {
+	transport := &contextTransport{ctx: context.Background(), base: http.DefaultTransport}
+	b, err := tele.NewBot(tele.Settings{
+		Token:   token,
+		Client:  &http.Client{Transport: transport, Timeout: time.Minute},
+		Offline: true,
+	})
 	if err != nil {
-		log.Println("Telegram Bot 初始化失敗:", err)
-		return
+		return nil, fmt.Errorf("Telegram Bot 初始化失敗: %w", err)
 	}
+	return &TelegramNotifier{ChatIDs: chatIDs, bot: b, transport: transport}, nil
+}
+
+// Notify 發送 Telegram 通知給所有 Chat ID
+func (n *TelegramNotifier) Notify(ctx context.Context, msg string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.transport.ctx = ctx
+	defer func() { n.transport.ctx = context.Background() }()
 
 	// 1. 使用逗號切割 ID 字串
-	ids := strings.Split(tgChatIDs, ",")
+	ids := strings.Split(n.ChatIDs, ",")
 
 	for _, idStr := range ids {
 		// 2. 去除前後空白 (避免設定變數時多打空白導致錯誤)
@@ -166,9 +226,14 @@ func SendAlert(tgToken, tgChatIDs, msg string) {
 			continue // 跳過這個錯誤的 ID，繼續發送給下一個
 		}
 
+		if ctx.Err() != nil {
+			log.Printf("❌ 通知中斷，未發送給 ID [%d]: %v\n", chatID, ctx.Err())
+			continue
+		}
+
 		// 4. 發送訊息
 		user := &tele.User{ID: chatID}
-		_, err = b.Send(user, msg)
+		_, err = n.bot.Send(user, msg)
 		if err != nil {
 			log.Printf("❌ 發送給 ID [%d] 失敗: %v\n", chatID, err)
 		} else {
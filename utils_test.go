@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetSessionType(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2026, 10, 16, hour, min, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name        string
+		now         time.Time
+		wantSession string
+		wantTrading bool
+	}{
+		{"夜盤收盤後", at(5, 1), SessionClosed, false},
+		{"早盤開盤前一分鐘", at(8, 44), SessionClosed, false},
+		{"早盤開盤", at(8, 45), SessionMorning, true},
+		{"現貨收盤", at(13, 30), SessionMorning, true},
+		{"早盤收盤", at(13, 45), SessionMorning, true},
+		{"早盤收盤後", at(13, 46), SessionClosed, false},
+		{"夜盤開盤前一分鐘", at(14, 59), SessionClosed, false},
+		{"夜盤開盤", at(15, 0), SessionNight, true},
+		{"夜盤跨日前", at(23, 59), SessionNight, true},
+		{"夜盤跨日", at(0, 0), SessionNight, true},
+		{"夜盤收盤", at(5, 0), SessionNight, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, trading := GetSessionType(NewFakeClock(tt.now), loc)
+			if session != tt.wantSession || trading != tt.wantTrading {
+				t.Errorf("GetSessionType(%s) = (%s, %v), want (%s, %v)",
+					tt.now.Format("15:04"), session, trading, tt.wantSession, tt.wantTrading)
+			}
+		})
+	}
+}
+
+func TestIsTaipexPreOpen(t *testing.T) {
+	tests := []struct {
+		hhmm int
+		want bool
+	}{
+		{844, false},
+		{845, true},
+		{900, true},
+		{901, false},
+	}
+
+	for _, tt := range tests {
+		now := time.Date(2026, 10, 16, tt.hhmm/100, tt.hhmm%100, 30, 0, loc)
+		if got := IsTaipexPreOpen(NewFakeClock(now), loc); got != tt.want {
+			t.Errorf("IsTaipexPreOpen(%04d) = %v, want %v", tt.hhmm, got, tt.want)
+		}
+	}
+}
+
+func TestIsTodayInDateList(t *testing.T) {
+	list := "2026-01-01, 2026-10-09,2026-10-10"
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"清單中的日期", time.Date(2026, 10, 9, 10, 0, 0, 0, loc), true},
+		{"含空白的日期", time.Date(2026, 10, 9, 0, 0, 0, 0, loc), true},
+		{"不在清單中", time.Date(2026, 10, 16, 10, 0, 0, 0, loc), false},
+		// UTC 仍是 10/8，但台北已是 10/9
+		{"以台北日期判斷", time.Date(2026, 10, 8, 16, 30, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTodayInDateList(NewFakeClock(tt.now), list, loc); got != tt.want {
+				t.Errorf("IsTodayInDateList(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+
+	if IsTodayInDateList(NewFakeClock(time.Now()), "", loc) {
+		t.Errorf("IsTodayInDateList() with empty list want false")
+	}
+}
+
+func TestIsUSMarketInWinterTime(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time // 台北時間
+		want bool
+	}{
+		{"一月_冬令", time.Date(2026, 1, 15, 22, 30, 0, 0, loc), true},
+		{"七月_夏令", time.Date(2026, 7, 15, 21, 30, 0, 0, loc), false},
+		// 2026-03-08 02:00 (美東) 開始夏令時間 = 台北 15:00
+		{"進入夏令前", time.Date(2026, 3, 8, 14, 59, 0, 0, loc), true},
+		{"進入夏令後", time.Date(2026, 3, 8, 15, 0, 0, 0, loc), false},
+		// 2026-11-01 02:00 (美東夏令) 結束夏令時間 = 台北 14:00
+		{"結束夏令前", time.Date(2026, 11, 1, 13, 59, 0, 0, loc), false},
+		{"結束夏令後", time.Date(2026, 11, 1, 14, 0, 0, 0, loc), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsUSMarketInWinterTime(NewFakeClock(tt.now))
+			if err != nil {
+				t.Fatalf("IsUSMarketInWinterTime() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsUSMarketInWinterTime(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2026, 10, 16, 13, 44, 0, 0, loc)
+	clock := NewFakeClock(start)
+
+	if session, _ := GetSessionType(clock, loc); session != SessionMorning {
+		t.Errorf("GetSessionType() = %s, want %s", session, SessionMorning)
+	}
+	clock.Advance(2 * time.Minute)
+	if session, _ := GetSessionType(clock, loc); session != SessionClosed {
+		t.Errorf("GetSessionType() after Advance = %s, want %s", session, SessionClosed)
+	}
+	clock.Set(start.Add(76 * time.Minute))
+	if session, _ := GetSessionType(clock, loc); session != SessionNight {
+		t.Errorf("GetSessionType() after Set = %s, want %s", session, SessionNight)
+	}
+}
+
+func TestTelegramNotifier_Notify(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{} // API 方法 -> 呼叫次數
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]]++
+		mu.Unlock()
+		w.Write([]byte(`{"ok":true,"result":{"message_id":1,"chat":{"id":1}}}`))
+	}))
+	defer srv.Close()
+
+	n, err := NewTelegramNotifier("test-token", "111, 222")
+	if err != nil {
+		t.Fatalf("NewTelegramNotifier() unexpected error: %v", err)
+	}
+	n.bot.URL = srv.URL
+
+	// 常駐模式多次通知共用同一個 Bot，不會每次都呼叫 getMe
+	n.Notify(context.Background(), "第一則")
+	n.Notify(context.Background(), "第二則")
+
+	// ctx 已取消時不發送
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n.Notify(ctx, "第三則")
+
+	if calls["sendMessage"] != 4 || calls["getMe"] != 0 {
+		t.Errorf("Notify() API calls = %v, want 4 sendMessage and no getMe", calls)
+	}
+}